@@ -0,0 +1,82 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+
+	"github.com/libdns/libdns"
+)
+
+// UpdateAddress points `name` at `ip`, as a dynamic DNS client would. If `name` already has an
+// address record of the same family (A for IPv4, AAAA for IPv6) it is replaced. If it only has
+// records of the other family, e.g. because the host moved from IPv4 to IPv6, the new record is
+// created first and then the old records are deleted. The TTL of any existing record is preserved.
+//
+// Returns the record as written.
+func (p *Provider) UpdateAddress(ctx context.Context, zone string, name string, ip netip.Addr) (libdns.Record, error) {
+	name, err := relativeName(name, zone)
+
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	ip = ip.Unmap()
+	recordType := "A"
+
+	if ip.Is6() {
+		recordType = "AAAA"
+	}
+
+	existing, err := p.getRecords(ctx, zone, "", "")
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return libdns.Record{}, err
+	}
+
+	record := libdns.Record{
+		Type:  recordType,
+		Name:  name,
+		Value: ip.String(),
+	}
+
+	var stale []libdns.Record
+	sameFamily := false
+
+	for _, r := range existing {
+		if r.Name != name || (r.Type != "A" && r.Type != "AAAA") {
+			continue
+		}
+
+		record.TTL = r.TTL
+
+		if r.Type == recordType {
+			sameFamily = true
+		} else {
+			stale = append(stale, r)
+		}
+	}
+
+	if sameFamily || len(stale) == 0 {
+		_, err = p.SetRecords(ctx, zone, []libdns.Record{record})
+		return record, err
+	}
+
+	// Both steps are checked before the first, so that an invalid stale record can't leave the name
+	// with addresses of both families
+	_, err = p.validateRecords(ctx, append([]libdns.Record{record}, stale...))
+
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	_, err = p.AppendRecords(ctx, zone, []libdns.Record{record})
+
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	_, err = p.DeleteRecords(ctx, zone, stale)
+	return record, err
+}
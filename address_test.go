@@ -0,0 +1,70 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+// Returns a provider backed by a server holding a single A record for "home", along with a pointer
+// to the list of "verb type data" strings for each mutation made against it.
+func newAddressTestProvider(t *testing.T) (*Provider, *[]string) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[{"name": "home", "type": "A", "data": "192.0.2.1", "ttl": 600, "scope": "member"}]`))
+		case "/dns/example.com/replaceRRSet":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("type")+" "+r.PostForm.Get("data"))
+		}
+	})
+
+	return p, &mutations
+}
+
+func TestUpdateAddressSameFamily(t *testing.T) {
+	p, mutations := newAddressTestProvider(t)
+
+	record, err := p.UpdateAddress(context.Background(), "example.com.", "home", netip.MustParseAddr("192.0.2.2"))
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"replaceRR A 192.0.2.2"}
+
+	if !reflect.DeepEqual(*mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, *mutations)
+	}
+
+	if record.Type != "A" || record.TTL.Seconds() != 600 {
+		t.Errorf("Unexpected record %+v", record)
+	}
+}
+
+func TestUpdateAddressCrossFamily(t *testing.T) {
+	p, mutations := newAddressTestProvider(t)
+
+	record, err := p.UpdateAddress(context.Background(), "example.com.", "home", netip.MustParseAddr("2001:db8::1"))
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"addRR AAAA 2001:db8::1", "removeRR A 192.0.2.1"}
+
+	if !reflect.DeepEqual(*mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, *mutations)
+	}
+
+	if record.Type != "AAAA" || record.TTL.Seconds() != 600 {
+		t.Errorf("Unexpected record %+v", record)
+	}
+}
@@ -0,0 +1,87 @@
+package nfsn
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Default value of `CircuitBreakerCooldown`
+const defaultCircuitBreakerCooldown = time.Minute
+
+// ErrCircuitOpen is returned without making a request while the circuit breaker (see
+// `Provider.CircuitBreakerThreshold`) is open.
+var ErrCircuitOpen = errors.New("Circuit breaker is open after repeated API failures")
+
+func (p *Provider) circuitBreakerCooldown() time.Duration {
+	if p.CircuitBreakerCooldown > 0 {
+		return p.CircuitBreakerCooldown
+	}
+
+	return defaultCircuitBreakerCooldown
+}
+
+// Returns an error wrapping ErrCircuitOpen if the circuit breaker is open, otherwise nil. Once the
+// cooldown has passed the breaker is half-open: a single trial request is let through, and returned
+// as a `probe`, and others are refused until its outcome is recorded or it's abandoned.
+func (p *Provider) checkCircuit() (probe bool, err error) {
+	if p.CircuitBreakerThreshold <= 0 {
+		return false, nil
+	}
+
+	p.circuitMtx.Lock()
+	defer p.circuitMtx.Unlock()
+
+	if remaining := time.Until(p.circuitOpenUntil); remaining > 0 {
+		return false, fmt.Errorf("%w: requests resume in %s", ErrCircuitOpen, remaining.Round(time.Second))
+	}
+
+	if p.consecutiveFailures < p.CircuitBreakerThreshold {
+		return false, nil
+	}
+
+	if p.circuitProbing {
+		return false, fmt.Errorf("%w: waiting for a trial request", ErrCircuitOpen)
+	}
+
+	p.circuitProbing = true
+	return true, nil
+}
+
+// Ends a trial request let through by `checkCircuit` without an outcome, e.g. because the caller
+// gave up, so that another request can be tried.
+func (p *Provider) abandonCircuitProbe() {
+	p.circuitMtx.Lock()
+	defer p.circuitMtx.Unlock()
+
+	p.circuitProbing = false
+}
+
+// Records the outcome of a request attempt, which was a trial request if `probe` is true. The
+// breaker opens once `CircuitBreakerThreshold` attempts in a row have failed. The count is only
+// reset by a success, so once the cooldown has passed a failed trial request opens it again.
+func (p *Provider) recordCircuitOutcome(probe bool, failed bool) {
+	if p.CircuitBreakerThreshold <= 0 {
+		return
+	}
+
+	p.circuitMtx.Lock()
+	defer p.circuitMtx.Unlock()
+
+	if probe {
+		p.circuitProbing = false
+	}
+
+	if !failed {
+		p.consecutiveFailures = 0
+		return
+	}
+
+	p.consecutiveFailures++
+
+	if p.consecutiveFailures >= p.CircuitBreakerThreshold {
+		cooldown := p.circuitBreakerCooldown()
+		p.circuitOpenUntil = time.Now().Add(cooldown)
+		p.logf("%d API requests in a row failed, pausing requests for %s", p.consecutiveFailures, cooldown)
+	}
+}
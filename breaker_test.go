@@ -0,0 +1,163 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	requests := 0
+	healthy := false
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	p.MaxAttempts = 1
+	p.CircuitBreakerThreshold = 2
+	p.CircuitBreakerCooldown = 50 * time.Millisecond
+
+	for i := 0; i < 2; i++ {
+		_, err := p.GetRecords(context.Background(), "example.com.")
+
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected the request to be sent and fail but got %v", err)
+		}
+	}
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if !errors.Is(err, ErrCircuitOpen) || requests != 2 {
+		t.Fatalf("Expected ErrCircuitOpen without a request but got %v after %d requests", err, requests)
+	}
+
+	// A single failure after the cooldown opens the breaker again
+	time.Sleep(60 * time.Millisecond)
+	p.GetRecords(context.Background(), "example.com.")
+	_, err = p.GetRecords(context.Background(), "example.com.")
+
+	if !errors.Is(err, ErrCircuitOpen) || requests != 3 {
+		t.Fatalf("Expected ErrCircuitOpen after 3 requests but got %v after %d", err, requests)
+	}
+
+	// A success closes it
+	time.Sleep(60 * time.Millisecond)
+	healthy = true
+
+	for i := 0; i < 2; i++ {
+		_, err = p.GetRecords(context.Background(), "example.com.")
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	// Client errors don't count as failures
+	p = newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	p.CircuitBreakerThreshold = 1
+
+	for i := 0; i < 2; i++ {
+		if _, err = p.GetRecords(context.Background(), "example.com."); errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected 400 responses not to open the breaker")
+		}
+	}
+}
+
+func TestCircuitBreakerIgnoresCallerTimeouts(t *testing.T) {
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests%2 == 0 {
+			<-r.Context().Done()
+			return
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	p.MaxAttempts = 1
+	p.CircuitBreakerThreshold = 2
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := p.GetRecords(ctx, "example.com.")
+		cancel()
+
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected the request to be sent and fail but got %v", err)
+		}
+	}
+
+	// The timeout in between neither counts as a failure nor resets the count
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := p.GetRecords(ctx, "example.com.")
+
+	if !errors.Is(err, ErrCircuitOpen) || requests != 3 {
+		t.Errorf("Expected ErrCircuitOpen after 3 requests but got %v after %d", err, requests)
+	}
+}
+
+func TestCircuitBreakerSendsOneTrialRequest(t *testing.T) {
+	healthy := make(chan struct{})
+	trial := make(chan struct{})
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		select {
+		case <-healthy:
+			// The trial request waits until the others have been tried
+			if requests == 2 {
+				close(trial)
+				time.Sleep(100 * time.Millisecond)
+			}
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	p.MaxAttempts = 1
+	p.CircuitBreakerThreshold = 1
+	p.CircuitBreakerCooldown = 50 * time.Millisecond
+
+	p.GetRecords(context.Background(), "example.com.")
+	time.Sleep(60 * time.Millisecond)
+	close(healthy)
+
+	done := make(chan error)
+
+	go func() {
+		_, err := p.GetRecords(context.Background(), "example.com.")
+		done <- err
+	}()
+
+	// A different listing, so it doesn't share the trial request
+	<-trial
+	_, err := p.GetRecordsFiltered(context.Background(), "example.com.", RecordFilter{Type: "A"})
+
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen during the trial request but got %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// The trial succeeded, so the breaker is closed
+	_, err = p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil || requests != 3 {
+		t.Errorf("Expected the breaker to close but got %v after %d requests", err, requests)
+	}
+}
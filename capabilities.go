@@ -0,0 +1,98 @@
+package nfsn
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/libdns/libdns"
+)
+
+// Capabilities describes optional NFSN API features. NFSN does not publish a version or capability
+// endpoint, so these are detected by probing the API (see `Provider.Capabilities`).
+type Capabilities struct {
+	// ReplaceRRSet is true if the API supports replacing every record for a (name, type) pair in a
+	// single request.
+	ReplaceRRSet bool
+}
+
+// Capabilities probes the NFSN API for optional features of `zone`. The result is cached on the
+// Provider per zone, so only the first call for each zone makes requests.
+//
+// Probes have no side effects: NFSN only calls verbs requested with POST, so a verb is requested
+// with GET instead. A verb is supported if the API responds with a success status, 400 Bad Request
+// or 405 Method Not Allowed, and unsupported if it responds with 404 Not Found. Any other response
+// (e.g. 401, 429 or 5xx) is returned as an error and not cached, so a later call probes again.
+func (p *Provider) Capabilities(ctx context.Context, zone string) (Capabilities, error) {
+	p.capabilitiesMtx.Lock()
+	capabilities, ok := p.capabilities[zone]
+	p.capabilitiesMtx.Unlock()
+
+	if ok {
+		return capabilities, nil
+	}
+
+	replaceRRSet, err := p.probeVerb(ctx, zone, "replaceRRSet")
+
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	capabilities = Capabilities{
+		ReplaceRRSet: replaceRRSet,
+	}
+
+	p.capabilitiesMtx.Lock()
+	defer p.capabilitiesMtx.Unlock()
+
+	if p.capabilities == nil {
+		p.capabilities = make(map[string]Capabilities)
+	}
+
+	p.capabilities[zone] = capabilities
+	return capabilities, nil
+}
+
+// Requests `verb` with GET, which doesn't call it. Returns true if the API accepts the verb, false if
+// it reports that the verb doesn't exist, and an error for any response that doesn't settle either
+// way. NFSN responds the same way to verbs on a zone that doesn't exist, so that's ruled out before
+// reporting a verb unsupported.
+func (p *Provider) probeVerb(ctx context.Context, zone string, verb string) (bool, error) {
+	verbURL := p.uriForZone(zone, verb)
+	resp, err := p.sendRequest(ctx, "GET", verbURL, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300,
+		resp.StatusCode == http.StatusBadRequest,
+		resp.StatusCode == http.StatusMethodNotAllowed:
+		return true, nil
+	case resp.StatusCode != http.StatusNotFound:
+		return false, p.apiError(resp, bodyBytes)
+	}
+
+	if zoneErr := p.zoneNotFoundError(ctx, verbURL, resp, p.apiError(resp, bodyBytes)); zoneErr != nil {
+		return false, zoneErr
+	}
+
+	return false, nil
+}
+
+// Replace each (name, type) group in `records` with a single `replaceRRSet` request. If only some
+// groups are replaced, returns the records in those groups _and_ an error.
+func (p *Provider) replaceRecordSets(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	ctx = p.withZoneMinTTL(ctx, zone)
+	ops, err := p.replaceOperations(ctx, records, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	replaced, err := p.applyOperations(ctx, zone, ops)
+	return replaced, orderBatchError(err, records)
+}
@@ -0,0 +1,130 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestCapabilitiesDriveSetRecords(t *testing.T) {
+	cases := []struct {
+		probeStatus  int
+		expectedVerb string
+	}{
+		{http.StatusBadRequest, "replaceRRSet"},
+		{http.StatusNotFound, "replaceRR"},
+	}
+
+	for _, c := range cases {
+		var paths []string
+
+		p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			paths = append(paths, r.URL.Path)
+
+			// The probe must not call the verb
+			if strings.HasSuffix(r.URL.Path, "/replaceRRSet") && r.Method == http.MethodGet {
+				w.WriteHeader(c.probeStatus)
+			}
+		})
+
+		records := []libdns.Record{
+			{Type: "A", Name: "www", Value: "192.0.2.1"},
+			{Type: "A", Name: "www", Value: "192.0.2.2"},
+		}
+
+		_, err := p.SetRecords(context.Background(), "example.com.", records)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		// The first request is the probe
+		expected := "/dns/example.com/" + c.expectedVerb
+
+		if paths[1] != expected {
+			t.Errorf("Expected '%s' but got '%s'", expected, paths[1])
+		}
+
+		// The probe result is cached, so a second call must not probe again
+		paths = nil
+		_, err = p.SetRecords(context.Background(), "example.com.", records[:1])
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if len(paths) != 1 {
+			t.Errorf("Expected 1 request but got %d", len(paths))
+		}
+
+		// Other zones are probed separately
+		paths = nil
+		_, err = p.SetRecords(context.Background(), "example.net.", records[:1])
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if len(paths) != 2 || paths[0] != "/dns/example.net/replaceRRSet" {
+			t.Errorf("Expected example.net to be probed but got %v", paths)
+		}
+	}
+}
+
+func TestCapabilitiesNotCachedForMissingZone(t *testing.T) {
+	probes := 0
+
+	p := newRawTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/replaceRRSet") {
+			probes++
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/dns/missing.example/") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	_, err := p.Capabilities(context.Background(), "missing.example.")
+
+	if !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("Expected ErrZoneNotFound but got %v", err)
+	}
+
+	capabilities, err := p.Capabilities(context.Background(), "example.com.")
+
+	if err != nil || !capabilities.ReplaceRRSet || probes != 2 {
+		t.Errorf("Expected replaceRRSet to be probed again and supported but got %+v, %v after %d probes", capabilities, err, probes)
+	}
+}
+
+func TestCapabilitiesNotCachedForServerError(t *testing.T) {
+	probes := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/replaceRRSet") {
+			probes++
+
+			if probes == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}
+	})
+
+	_, err := p.Capabilities(context.Background(), "example.com.")
+	var apiErr *APIError
+
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected a 503 APIError but got %v", err)
+	}
+
+	capabilities, err := p.Capabilities(context.Background(), "example.com.")
+
+	if err != nil || !capabilities.ReplaceRRSet || probes != 2 {
+		t.Errorf("Expected replaceRRSet to be probed again and supported but got %+v, %v after %d probes", capabilities, err, probes)
+	}
+}
@@ -0,0 +1,151 @@
+package nfsn
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// Changeset accumulates changes to a zone so that they can be reviewed with Preview and then made
+// together with Apply. NFSN has no transactions, so Apply can still fail part way through, but every
+// change is validated before the first is made and the result of each API call is reported.
+//
+// A Changeset is safe for concurrent use.
+type Changeset struct {
+	provider *Provider
+	zone     string
+
+	changes    []changesetEntry
+	changesMtx sync.Mutex
+}
+
+// A single call to Add, Replace, or Delete
+type changesetEntry struct {
+	verb    string
+	records []libdns.Record
+}
+
+// OperationResult is the outcome of one of the API calls made by Changeset.Apply.
+type OperationResult struct {
+	Operation Operation
+
+	// True if the call was made, i.e. no earlier call failed
+	Attempted bool
+
+	// The error the call failed with, if it failed
+	Err error
+}
+
+// NewChangeset returns an empty Changeset for `zone`.
+func (p *Provider) NewChangeset(zone string) *Changeset {
+	return &Changeset{provider: p, zone: zone}
+}
+
+// Add queues `records` to be added, as AppendRecords would.
+func (c *Changeset) Add(records ...libdns.Record) *Changeset {
+	return c.queue("addRR", records)
+}
+
+// Replace queues the (name, type) sets in `records` to be replaced, as SetRecords would.
+func (c *Changeset) Replace(records ...libdns.Record) *Changeset {
+	return c.queue("replaceRR", records)
+}
+
+// Delete queues `records` to be deleted. Unlike DeleteRecords, each record must have a value; use
+// DeleteRRSet to delete a set by name and type.
+func (c *Changeset) Delete(records ...libdns.Record) *Changeset {
+	return c.queue("removeRR", records)
+}
+
+func (c *Changeset) queue(verb string, records []libdns.Record) *Changeset {
+	c.changesMtx.Lock()
+	defer c.changesMtx.Unlock()
+
+	c.changes = append(c.changes, changesetEntry{verb: verb, records: append([]libdns.Record(nil), records...)})
+	return c
+}
+
+// Preview returns the API calls Apply would make, in order, without making them. Changes are made in
+// the order they were queued. Returns an error, identifying the change, if any queued record is
+// invalid.
+func (c *Changeset) Preview(ctx context.Context) ([]Operation, error) {
+	return c.operations(c.provider.withZoneMinTTL(ctx, c.zone))
+}
+
+// Returns the API calls that make every queued change, in order.
+func (c *Changeset) operations(ctx context.Context) ([]Operation, error) {
+	c.changesMtx.Lock()
+	changes := append([]changesetEntry(nil), c.changes...)
+	c.changesMtx.Unlock()
+
+	var ops []Operation
+
+	for i, change := range changes {
+		changeOps, err := c.changeOperations(ctx, change)
+
+		if err != nil {
+			return nil, fmt.Errorf("Change %d of %d is invalid: %w", i+1, len(changes), err)
+		}
+
+		ops = append(ops, changeOps...)
+	}
+
+	return ops, nil
+}
+
+// Returns the API calls that make `change`.
+func (c *Changeset) changeOperations(ctx context.Context, change changesetEntry) ([]Operation, error) {
+	records, err := relativeRecords(c.zone, change.records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch change.verb {
+	case "replaceRR":
+		return c.provider.replaceOperations(ctx, records, false)
+	case "removeRR":
+		for _, record := range records {
+			if record.Value == "" {
+				return nil, fmt.Errorf("%s record %q has no value to delete", record.Type, record.Name)
+			}
+		}
+	}
+
+	return c.provider.recordOperations(ctx, change.verb, records)
+}
+
+// Apply makes the queued changes, returning the result of each API call in order. Every change is
+// validated first, so an invalid change means no calls are made. Calls stop at the first failure,
+// whose error is also returned; the results of the calls after it have Attempted set to false.
+//
+// The Changeset is left as it is, so Apply should not be called again once the changes are made.
+func (c *Changeset) Apply(ctx context.Context) ([]OperationResult, error) {
+	p := c.provider
+	ctx = p.withZoneMinTTL(ctx, c.zone)
+	ops, err := c.operations(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = p.withRetryBudget(ctx)
+	results := make([]OperationResult, len(ops))
+
+	for i, op := range ops {
+		results[i].Operation = op
+	}
+
+	for i := range results {
+		results[i].Attempted = true
+		results[i].Err = p.applyOperation(ctx, c.zone, results[i].Operation)
+
+		if results[i].Err != nil {
+			return results, results[i].Err
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,80 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestChangeset(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mutation := r.URL.Path[len("/dns/example.com/"):] + " " + r.PostForm.Get("type") + " " + r.PostForm.Get("name") + " " + r.PostForm.Get("data")
+		mutations = append(mutations, mutation)
+
+		if r.PostForm.Get("data") == "192.0.2.3" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	changes := p.NewChangeset("example.com.").
+		Delete(libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "old"}).
+		Replace(libdns.Record{Type: "A", Name: "www.example.com.", Value: "192.0.2.1"}, libdns.Record{Type: "A", Name: "www", Value: "192.0.2.2"}).
+		Add(libdns.Record{Type: "A", Name: "mail", Value: "192.0.2.3"}, libdns.Record{Type: "A", Name: "ftp", Value: "192.0.2.4"})
+
+	ops, err := changes.Preview(context.Background())
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"removeRR TXT _acme-challenge old",
+		"replaceRR A www 192.0.2.1",
+		"addRR A www 192.0.2.2",
+		"addRR A mail 192.0.2.3",
+		"addRR A ftp 192.0.2.4",
+	}
+
+	if !reflect.DeepEqual(describeOperations(ops), expected) {
+		t.Errorf("Expected %v but got %v", expected, describeOperations(ops))
+	}
+
+	if len(mutations) != 0 {
+		t.Errorf("Expected no changes from Preview but got %v", mutations)
+	}
+
+	results, err := changes.Apply(context.Background())
+
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if len(mutations) != 4 || len(results) != 5 {
+		t.Fatalf("Expected 4 of 5 calls but got %v, %+v", mutations, results)
+	}
+
+	for i, result := range results {
+		if result.Attempted != (i < 4) || (result.Err != nil) != (i == 3) {
+			t.Errorf("Unexpected result %d: %+v", i, result)
+		}
+	}
+
+	// Invalid changes prevent every call
+	mutations = nil
+	changes.Delete(libdns.Record{Type: "TXT", Name: "_acme-challenge"})
+
+	if _, err = changes.Apply(context.Background()); err == nil || !strings.Contains(err.Error(), "Change 4 of 4") {
+		t.Errorf("Expected an error identifying change 4 but got %v", err)
+	}
+
+	if len(mutations) != 0 {
+		t.Errorf("Expected no changes but got %v", mutations)
+	}
+}
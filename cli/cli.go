@@ -19,6 +19,7 @@ const(
 	OperationAddRecord = "AddRecord"
 	OperationDeleteRecord = "DeleteRecord"
 	OperationGetRecords = "GetRecords"
+	OperationListZones = "ListZones"
 	OperationSetRecord = "SetRecord"
 )
 
@@ -34,6 +35,8 @@ func (o *operation) Set(value string) error {
 		*o = OperationDeleteRecord
 	case OperationGetRecords:
 		*o = OperationGetRecords
+	case OperationListZones:
+		*o = OperationListZones
 	case OperationSetRecord:
 		*o = OperationSetRecord
 	default:
@@ -68,7 +71,7 @@ func main() {
 	tArg := flag.String("t", "", "The type of record to operate on")
 	nArg := flag.String("n", "", "The name of the record to operate on")
 	dArg := flag.String("d", "", "The record data to write, if applicable")
-	flag.Var(&oArg, "o", "The operation to perform. Supported values are: AddRecord, DeleteRecord, GetRecords, SetRecord")
+	flag.Var(&oArg, "o", "The operation to perform. Supported values are: AddRecord, DeleteRecord, GetRecords, ListZones, SetRecord")
 	flag.Parse()
 
 	apiKey, err := readApiKey(*fArg)
@@ -78,7 +81,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	p := nfsn.Provider{
+	p := &nfsn.Provider{
 		Login: *lArg,
 		APIKey: apiKey,
 	}
@@ -99,6 +102,21 @@ func main() {
 		for _, r := range records {
 			fmt.Printf("%+v\n\n", r)
 		}
+	case OperationListZones:
+		fmt.Printf("Listing zones in account %s...\n", p.Login)
+
+		zones, err := p.ListZones(context.TODO())
+
+		if err != nil {
+			fmt.Printf("Encountered error listing zones: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Print("Found zones:\n\n")
+
+		for _, z := range zones {
+			fmt.Println(z.Name)
+		}
 	case OperationAddRecord:
 		fallthrough
 	case OperationDeleteRecord:
@@ -0,0 +1,53 @@
+package nfsn
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrTimestampRejected is returned when NFSN rejects a request's auth timestamp even after
+// correcting for the skew between the local clock and NFSN's.
+var ErrTimestampRejected = errors.New("NFSN rejected the request timestamp")
+
+// Returns the current time according to NFSN's clock, as estimated from the Date headers of its
+// responses. NFSN rejects requests whose auth timestamp is more than a few seconds off, so the
+// timestamp in the auth header uses this rather than the local clock.
+func (p *Provider) now() time.Time {
+	p.clockSkewMtx.Lock()
+	defer p.clockSkewMtx.Unlock()
+
+	return time.Now().Add(p.clockSkew)
+}
+
+// Updates the estimated offset of NFSN's clock from the local one using the Date header of `resp`.
+// The header only has a resolution of a second, so smaller offsets are ignored.
+func (p *Provider) observeDate(resp *http.Response) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+
+	if err != nil {
+		return
+	}
+
+	skew := time.Until(date)
+
+	if skew > -time.Second && skew < time.Second {
+		skew = 0
+	}
+
+	p.clockSkewMtx.Lock()
+	defer p.clockSkewMtx.Unlock()
+
+	if skew != p.clockSkew {
+		p.logf("Clock skew from NFSN is now %s", skew)
+	}
+
+	p.clockSkew = skew
+}
+
+// Returns true if `resp` is NFSN rejecting a request because its auth timestamp was too far from
+// the server's clock.
+func isTimestampError(resp *http.Response, body []byte) bool {
+	return resp.StatusCode == http.StatusUnauthorized && bytes.Contains(bytes.ToLower(body), []byte("timestamp"))
+}
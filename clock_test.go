@@ -0,0 +1,70 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockSkew(t *testing.T) {
+	serverTime := time.Now().Add(time.Hour)
+	var timestamps []int64
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.Header.Get(authHeader), ";")
+		timestamp := mustAtoi(t, parts[1])
+		timestamps = append(timestamps, timestamp)
+		w.Header().Set("Date", serverTime.UTC().Format(http.TimeFormat))
+
+		if timestamp < serverTime.Unix()-5 || timestamp > serverTime.Unix()+5 {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Authentication error.","debug":"The timestamp is too far from the current time."}`))
+			return
+		}
+
+		w.Write([]byte("[]"))
+	})
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(timestamps) != 2 {
+		t.Fatalf("Expected 2 requests but got %d", len(timestamps))
+	}
+
+	_, err = p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(timestamps) != 3 {
+		t.Errorf("Expected the skew to be remembered, but got %d requests", len(timestamps))
+	}
+}
+
+func TestTimestampRejected(t *testing.T) {
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"Authentication error.","debug":"The timestamp is too far from the current time."}`))
+	})
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if !errors.Is(err, ErrTimestampRejected) {
+		t.Errorf("Expected ErrTimestampRejected but got %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests but got %d", requests)
+	}
+}
@@ -0,0 +1,39 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+
+	"github.com/libdns/libdns"
+)
+
+// Returns a ConflictError for the first of `records` that would put a CNAME alongside another
+// record with the same name, either in the zone or earlier in `records`. Names are compared as NFSN
+// stores them, so "@" matches records at the apex.
+func (p *Provider) checkConflicts(ctx context.Context, zone string, records []libdns.Record) error {
+	existing, err := p.getRecords(ctx, zone, "", "")
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return err
+	}
+
+	for _, record := range records {
+		name := rrSetKeyFor(record).name
+
+		for _, e := range existing {
+			if rrSetKeyFor(e).name != name {
+				continue
+			}
+
+			if record.Type == "CNAME" || e.Type == "CNAME" {
+				return &ConflictError{Record: record, Existing: e}
+			}
+		}
+
+		// Later records mustn't conflict with this one either
+		existing = append(existing, record)
+	}
+
+	return nil
+}
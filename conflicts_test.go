@@ -0,0 +1,88 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestAppendRecordsConflict(t *testing.T) {
+	mutations := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns/example.com/listRRs" {
+			w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"}]`))
+		} else {
+			mutations++
+		}
+	})
+	p.CheckConflicts = true
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "www", Value: "fine"},
+		{Type: "CNAME", Name: "www", Value: "example.net."},
+	}
+
+	_, err := p.AppendRecords(context.Background(), "example.com.", records)
+	var conflict *ConflictError
+
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected ConflictError but got %v", err)
+	}
+
+	if conflict.Record.Type != "CNAME" || conflict.Existing.Type != "A" || conflict.Existing.Value != "192.0.2.1" {
+		t.Errorf("Unexpected conflict %+v", conflict)
+	}
+
+	if mutations != 0 {
+		t.Errorf("Expected no records to be written but %d were", mutations)
+	}
+
+	_, err = p.AppendRecords(context.Background(), "example.com.", records[:1])
+
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestAppendRecordsConflictAtApex(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns/example.com/listRRs" {
+			w.Write([]byte(`[{"name": "", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"}]`))
+		}
+	})
+	p.CheckConflicts = true
+
+	for _, name := range []string{"@", "", "example.com."} {
+		_, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{{Type: "CNAME", Name: name, Value: "example.net."}})
+		var conflict *ConflictError
+
+		if !errors.As(err, &conflict) || conflict.Existing.Type != "A" {
+			t.Errorf("Expected a ConflictError with the apex A record for %q but got %v", name, err)
+		}
+	}
+}
+
+func TestAppendRecordsConflictWithinBatch(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns/example.com/listRRs" {
+			w.Write([]byte(`[]`))
+		}
+	})
+	p.CheckConflicts = true
+
+	records := []libdns.Record{
+		{Type: "A", Name: "WWW", Value: "192.0.2.1"},
+		{Type: "CNAME", Name: "www", Value: "example.net."},
+	}
+
+	_, err := p.AppendRecords(context.Background(), "example.com.", records)
+	var conflict *ConflictError
+
+	if !errors.As(err, &conflict) || conflict.Record.Type != "CNAME" || conflict.Existing.Type != "A" {
+		t.Errorf("Expected the CNAME to conflict with the A record but got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Checks that `value` is CAA data in zone file form, "flags tag value", and returns it with the
+// value quoted. Values that are already quoted are left exactly as they are, since they often
+// contain spaces or semicolons (e.g. `"letsencrypt.org; validationmethods=dns-01"`).
+func formatCAA(value string) (string, error) {
+	parts := strings.SplitN(value, " ", 3)
+
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%q is not in the form 'flags tag value'", value)
+	}
+
+	flags, tag, tagValue := parts[0], parts[1], parts[2]
+
+	if _, err := strconv.ParseUint(flags, 10, 8); err != nil {
+		return "", fmt.Errorf("flags %q must be a number from 0 to 255", flags)
+	}
+
+	if tag == "" || strings.IndexFunc(tag, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) >= 0 {
+		return "", fmt.Errorf("tag %q must be alphanumeric", tag)
+	}
+
+	if len(tagValue) < 2 || !strings.HasPrefix(tagValue, `"`) || !strings.HasSuffix(tagValue, `"`) {
+		tagValue = strconv.Quote(tagValue)
+	}
+
+	return fmt.Sprintf("%s %s %s", flags, tag, tagValue), nil
+}
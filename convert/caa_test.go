@@ -0,0 +1,59 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestCAARecord(t *testing.T) {
+	cases := []struct {
+		nRecord  RR
+		expected string
+	}{
+		{RR{Name: "", Type: "CAA", Data: `issue "letsencrypt.org"`, TTL: 3600}, `0 issue "letsencrypt.org"`},
+		{RR{Name: "", Type: "CAA", Data: `issuewild ";"`, TTL: 3600, Aux: 128}, `128 issuewild ";"`},
+		{RR{Name: "", Type: "CAA", Data: `0 issue "letsencrypt.org; validationmethods=dns-01"`, TTL: 3600}, `0 issue "letsencrypt.org; validationmethods=dns-01"`},
+	}
+
+	for _, c := range cases {
+		record, err := c.nRecord.Record()
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if record.Value != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, record.Value)
+		}
+	}
+}
+
+func TestCAAParameters(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{`0 issue "letsencrypt.org"`, `0 issue "letsencrypt.org"`},
+		{`0 issue "letsencrypt.org; validationmethods=dns-01"`, `0 issue "letsencrypt.org; validationmethods=dns-01"`},
+		{`0 iodef mailto:security@example.com`, `0 iodef "mailto:security@example.com"`},
+	}
+
+	for _, c := range cases {
+		record := libdns.Record{Type: "CAA", Name: "", Value: c.value}
+		data := mustFromLibdns(t, record, Options{}).Data
+
+		if data != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, data)
+		}
+	}
+
+	for _, value := range []string{`issue "letsencrypt.org"`, `256 issue "letsencrypt.org"`, `0 is-sue "letsencrypt.org"`} {
+		record := libdns.Record{Type: "CAA", Name: "", Value: value}
+		_, err := FromLibdns(record, Options{})
+
+		if err == nil {
+			t.Errorf("%s: Expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// Package convert converts DNS records between the form NFSN's API uses and libdns.Record, so that
+// tools other than the provider (importers, scripts) can reuse the mapping and its quirks, such as
+// the priority of some record types being reported separately in an `aux` field.
+package convert
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// MinimumTTL is the lowest TTL NFSN allows.
+const MinimumTTL = 180 * time.Second
+
+// RR is a DNS record as NFSN's API represents it, e.g. in the results of `listRRs`. TTL is in
+// seconds.
+type RR struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type,omitempty"`
+	Data  string `json:"data,omitempty"`
+	TTL   int    `json:"ttl,omitempty"`
+	Scope string `json:"scope,omitempty"`
+	Aux   int    `json:"aux,omitempty"`
+}
+
+// Options controls how records are converted.
+type Options struct {
+	// If true, AAAA addresses are written in canonical (RFC 5952) form. They are always read in
+	// that form.
+	CanonicalizeAddresses bool
+
+	// If true, TXT values containing control characters or non-ASCII characters, which NFSN doesn't
+	// reliably store as sent, are rejected.
+	StrictTXT bool
+
+	// Record types whose values are passed between libdns and NFSN exactly as they are, bypassing
+	// validation and formatting. NFSN's `aux` field is read as the priority. Types this package
+	// doesn't know about are always passed through.
+	PassthroughTypes []string
+}
+
+// Returns true if `rType` is one of `PassthroughTypes`.
+func (opts Options) isPassthrough(rType string) bool {
+	for _, passthrough := range opts.PassthroughTypes {
+		if strings.EqualFold(passthrough, rType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ToLibdns converts `rr` to a libdns.Record, as `RR.Record` does unless its type is one of
+// `opts.PassthroughTypes`.
+func ToLibdns(rr RR, opts Options) (libdns.Record, error) {
+	if opts.isPassthrough(rr.Type) {
+		return rr.RawRecord(), nil
+	}
+
+	return rr.Record()
+}
+
+// FromLibdns converts `record` to the NFSN form used to add, replace, or remove it, validating and
+// formatting its value for its type. The name is checked for misplaced wildcards (see
+// `WildcardName`) and decomposed characters (see `CheckComposed`), then converted to punycode (see
+// `ASCIIName`), and the TTL is converted to seconds as is; NFSN rejects TTLs below `MinimumTTL`.
+// Aux and Scope are never set, since NFSN expects any priority in the data when writing.
+//
+// Records read from NFSN and not since changed are given their data exactly as NFSN reported it,
+// so that they match the stored records byte for byte.
+func FromLibdns(record libdns.Record, opts Options) (RR, error) {
+	name, err := WildcardName(record.Name)
+
+	if err != nil {
+		return RR{}, err
+	}
+
+	err = CheckComposed(name)
+
+	if err != nil {
+		return RR{}, err
+	}
+
+	recordData, ok := rawData(record)
+
+	if !ok {
+		recordData, err = data(record, opts)
+
+		if err != nil {
+			return RR{}, err
+		}
+	}
+
+	rType := record.Type
+
+	// ANAME is another name for the apex flattening NFSN calls ALIAS
+	if rType == "ANAME" {
+		rType = "ALIAS"
+	}
+
+	return RR{
+		Name: ASCIIName(name),
+		Type: rType,
+		Data: recordData,
+		TTL:  int(record.TTL.Seconds()),
+	}, nil
+}
+
+// Record converts `rr` to a libdns.Record, interpreting its data according to its type. NFSN
+// stores the priority of MX, SRV, and URI records in `aux`, and the data of SRV and URI records is
+// "weight port target", of which weight is moved into the libdns.Record. The order and preference
+// of NAPTR records become the priority and weight. Quoted TXT strings are unquoted and joined.
+//
+// The libdns.Record's ID carries the NFSN scope and original data (see `Scope`), so the record
+// doesn't compare equal with `==` to one built without them; compare records with `Equal`.
+func (rr RR) Record() (libdns.Record, error) {
+	record := libdns.Record{
+		Type:  rr.Type,
+		Name:  rr.Name,
+		Value: rr.Data,
+		TTL:   time.Second * time.Duration(rr.TTL),
+	}
+
+	record.ID = rr.metadata().Encode()
+
+	switch rr.Type {
+	case "AAAA":
+		// IPv6 addresses have many textual forms. Return the canonical (RFC 5952) one so that the
+		// value doesn't depend on how it was written to NFSN.
+		addr, err := netip.ParseAddr(rr.Data)
+
+		if err != nil {
+			return libdns.Record{}, err
+		}
+
+		record.Value = addr.String()
+	case "HTTPS", "SVCB":
+		// Data is "priority target params". NFSN may also report the priority in the 'aux' field, as
+		// it does for MX, in which case data is just "target params".
+		record.Priority = uint(rr.Aux)
+		parts := strings.SplitN(rr.Data, " ", 2)
+		priority, err := strconv.ParseUint(parts[0], 10, 16)
+
+		if err == nil && len(parts) == 2 {
+			record.Priority = uint(priority)
+			record.Value = parts[1]
+		} else if rr.Aux == 0 {
+			return libdns.Record{}, fmt.Errorf("%s record %s has incorrect format", rr.Name, rr.Data)
+		}
+	case "TXT":
+		record.Value = joinTXT(rr.Data)
+	case "CAA":
+		// Data is "tag value", or "flags tag value" in zone file form. libdns expects the latter.
+		if _, err := strconv.ParseUint(strings.SplitN(rr.Data, " ", 2)[0], 10, 8); err != nil {
+			record.Value = fmt.Sprintf("%d %s", rr.Aux, rr.Data)
+		}
+	case "MX":
+		record.Priority = uint(rr.Aux)
+	case "NAPTR":
+		// Data is "order preference flags service regexp replacement". libdns expects order as the
+		// priority and preference as the weight. As with HTTPS, NFSN may report the order in the
+		// 'aux' field instead, in which case data starts with the preference.
+		record.Priority = uint(rr.Aux)
+		parts := strings.SplitN(rr.Data, " ", 3)
+
+		if len(parts) == 3 && isUint16(parts[1]) {
+			order, err := strconv.ParseUint(parts[0], 10, 16)
+
+			if err != nil {
+				return libdns.Record{}, fmt.Errorf("%s record %s has incorrect format", rr.Name, rr.Data)
+			}
+
+			record.Priority = uint(order)
+			parts = parts[1:]
+		} else {
+			parts = strings.SplitN(rr.Data, " ", 2)
+		}
+
+		if len(parts) != 2 {
+			return libdns.Record{}, fmt.Errorf("%s record %s has incorrect format", rr.Name, rr.Data)
+		}
+
+		preference, err := strconv.ParseUint(parts[0], 10, 16)
+
+		if err != nil {
+			return libdns.Record{}, fmt.Errorf("%s record %s has incorrect format", rr.Name, rr.Data)
+		}
+
+		record.Weight = uint(preference)
+		record.Value = parts[1]
+	case "SRV", "URI":
+		// Priority is in the 'aux' field from NFSN
+		record.Priority = uint(rr.Aux)
+
+		// Data is "weight port target", libdns expects weight in the record
+		parts := strings.SplitN(rr.Data, " ", 2)
+
+		if len(parts) != 2 {
+			return libdns.Record{}, fmt.Errorf("%s record %s has incorrect format", rr.Name, rr.Data)
+		}
+
+		weight, err := strconv.Atoi(parts[0])
+
+		if err != nil {
+			return libdns.Record{}, err
+		}
+
+		record.Weight = uint(weight)
+		record.Value = parts[1]
+	}
+
+	return record, nil
+}
+
+func isUint16(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 16)
+	return err == nil
+}
+
+// RawRecord returns `rr` as a libdns.Record without interpreting its data, see
+// `Options.PassthroughTypes`.
+func (rr RR) RawRecord() libdns.Record {
+	return libdns.Record{
+		ID:       rr.metadata().Encode(),
+		Type:     rr.Type,
+		Name:     rr.Name,
+		Value:    rr.Data,
+		TTL:      time.Second * time.Duration(rr.TTL),
+		Priority: uint(rr.Aux),
+	}
+}
+
+// Returns the canonical (RFC 5952) form of an IP address, or `value` unchanged if it can't be
+// parsed; NFSN will reject it with a more useful error than we could.
+func canonicalAddress(value string) string {
+	addr, err := netip.ParseAddr(value)
+
+	if err != nil {
+		return value
+	}
+
+	return addr.String()
+}
+
+// NFSN interprets hostname targets the way a zone file does: a target without a trailing dot is
+// relative to the zone, so "mail.example.net" in the example.com zone points at
+// "mail.example.net.example.com". Targets that contain a dot are assumed to be fully qualified and
+// have a trailing dot added. Single label targets (e.g. "www") are left relative to the zone.
+func qualifyTarget(target string) string {
+	if strings.Contains(target, ".") && !strings.HasSuffix(target, ".") {
+		return target + "."
+	}
+
+	return target
+}
+
+// Returns the data to send to NFSN for `record`, validating and normalizing its value.
+func data(record libdns.Record, opts Options) (string, error) {
+	if opts.isPassthrough(record.Type) {
+		return record.Value, nil
+	}
+
+	var dataBuilder strings.Builder
+	value := record.Value
+
+	switch record.Type {
+	case "AAAA":
+		if opts.CanonicalizeAddresses {
+			value = canonicalAddress(value)
+		}
+	case "ALIAS", "ANAME", "CNAME", "NS", "PTR":
+		value = qualifyTarget(value)
+	case "TXT":
+		txtValue, err := formatTXT(value, opts.StrictTXT)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid TXT record %q: %w", record.Name, err)
+		}
+
+		value = txtValue
+	case "CAA":
+		caaValue, err := formatCAA(value)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid CAA record %q: %w", record.Name, err)
+		}
+
+		value = caaValue
+	case "LOC":
+		locValue, err := formatLOC(value)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid LOC record %q: %w", record.Name, err)
+		}
+
+		value = locValue
+	case "SSHFP":
+		sshfpValue, err := formatSSHFP(value)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid SSHFP record %q: %w", record.Name, err)
+		}
+
+		value = sshfpValue
+	case "MX":
+		dataBuilder.WriteString(fmt.Sprintf("%d ", record.Priority))
+		value = qualifyTarget(value)
+	case "HTTPS", "SVCB":
+		// Value is "target params"; the priority is sent inline in the data
+		svcbValue, err := formatServiceBinding(value)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid %s record %q: %w", record.Type, record.Name, err)
+		}
+
+		dataBuilder.WriteString(fmt.Sprintf("%d ", record.Priority))
+		value = svcbValue
+	case "NAPTR":
+		// Priority is the order and weight the preference
+		naptrValue, err := formatNAPTR(value)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid NAPTR record %q: %w", record.Name, err)
+		}
+
+		dataBuilder.WriteString(fmt.Sprintf("%d %d ", record.Priority, record.Weight))
+		value = naptrValue
+	case "SRV":
+		srvValue, err := formatSRV(record.Name, value)
+
+		if err != nil {
+			return "", fmt.Errorf("Invalid SRV record %q: %w", record.Name, err)
+		}
+
+		dataBuilder.WriteString(fmt.Sprintf("%d %d ", record.Priority, record.Weight))
+		value = srvValue
+	case "URI":
+		dataBuilder.WriteString(fmt.Sprintf("%d %d ", record.Priority, record.Weight))
+	}
+
+	dataBuilder.WriteString(value)
+	return dataBuilder.String(), nil
+}
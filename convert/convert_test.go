@@ -0,0 +1,18 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func mustFromLibdns(t *testing.T, record libdns.Record, opts Options) RR {
+	t.Helper()
+	rr, err := FromLibdns(record, opts)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return rr
+}
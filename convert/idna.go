@@ -0,0 +1,279 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Prefix of an IDNA A-label (RFC 5890)
+const acePrefix = "xn--"
+
+// Names are converted with a minimal implementation of punycode (RFC 3492) rather than
+// golang.org/x/net/idna, which the module doesn't depend on. This isn't IDNA: no UTS #46 mapping is
+// applied other than lowercasing, and labels aren't validated; NFSN rejects names that aren't valid.
+// In particular names aren't normalized to NFC, so they must be given composed (see
+// `CheckComposed`), or the same name could be encoded as two different A-labels.
+
+// Punycode parameters (RFC 3492 section 5)
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// ASCIIName converts a domain name to the ASCII form NFSN expects, encoding each label with
+// non-ASCII characters as a punycode A-label. Labels are lowercased, but not otherwise mapped or
+// normalized (see `CheckComposed`). Labels that aren't valid UTF-8 are left as is for NFSN to reject.
+func ASCIIName(name string) string {
+	labels := strings.Split(name, ".")
+
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+
+		encoded, err := punycodeEncode(strings.ToLower(label))
+
+		if err == nil {
+			labels[i] = acePrefix + encoded
+		}
+	}
+
+	return strings.Join(labels, ".")
+}
+
+// CheckComposed returns an error if `name` has a label with a combining diacritical mark (U+0300 to
+// U+036F) after another character, e.g. "bu\u0308cher" rather than "bücher". Such labels are
+// usually not in NFC, and encoding them would give a different A-label from the composed name, so
+// they are rejected rather than normalized, which would need the Unicode composition tables. The
+// few NFC names that combine a letter with a mark it has no precomposed form for are rejected too.
+func CheckComposed(name string) error {
+	for _, label := range strings.Split(name, ".") {
+		for i, r := range label {
+			if i > 0 && r >= 0x0300 && r <= 0x036f {
+				return fmt.Errorf("Name %q has a combining mark (U+%04X); give it in composed (NFC) form", name, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnicodeName converts the A-labels in a domain name to Unicode. Labels that can't be decoded are
+// left as is.
+func UnicodeName(name string) string {
+	labels := strings.Split(name, ".")
+
+	for i, label := range labels {
+		if !strings.HasPrefix(strings.ToLower(label), acePrefix) {
+			continue
+		}
+
+		decoded, err := punycodeDecode(label[len(acePrefix):])
+
+		if err == nil {
+			labels[i] = decoded
+		}
+	}
+
+	return strings.Join(labels, ".")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+func punyAdapt(delta int, numPoints int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+
+	delta += delta / numPoints
+	k := 0
+
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func punyThreshold(k int, bias int) int {
+	switch {
+	case k <= bias+punyTMin:
+		return punyTMin
+	case k >= bias+punyTMax:
+		return punyTMax
+	default:
+		return k - bias
+	}
+}
+
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+
+	return byte('0' + d - 26)
+}
+
+// Encodes `label` with punycode (RFC 3492), without the A-label prefix.
+func punycodeEncode(label string) (string, error) {
+	if !utf8.ValidString(label) {
+		return "", fmt.Errorf("label %q is not valid UTF-8", label)
+	}
+
+	runes := []rune(label)
+	var out strings.Builder
+
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			out.WriteRune(r)
+		}
+	}
+
+	basic := out.Len()
+	handled := basic
+
+	if basic > 0 {
+		out.WriteByte('-')
+	}
+
+	n := punyInitialN
+	delta := 0
+	bias := punyInitialBias
+
+	for handled < len(runes) {
+		m := int(utf8.MaxRune) + 1
+
+		for _, r := range runes {
+			if int(r) >= n && int(r) < m {
+				m = int(r)
+			}
+		}
+
+		delta += (m - n) * (handled + 1)
+		n = m
+
+		for _, r := range runes {
+			if int(r) < n {
+				delta++
+			}
+
+			if int(r) != n {
+				continue
+			}
+
+			q := delta
+
+			for k := punyBase; ; k += punyBase {
+				t := punyThreshold(k, bias)
+
+				if q < t {
+					break
+				}
+
+				out.WriteByte(punyDigit(t + (q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+
+			out.WriteByte(punyDigit(q))
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+
+		delta++
+		n++
+	}
+
+	return out.String(), nil
+}
+
+// Decodes a punycode string (RFC 3492), without the A-label prefix.
+func punycodeDecode(encoded string) (string, error) {
+	var output []rune
+	pos := 0
+
+	if i := strings.LastIndexByte(encoded, '-'); i >= 0 {
+		for _, r := range encoded[:i] {
+			if r >= utf8.RuneSelf {
+				return "", fmt.Errorf("%q is not valid punycode", encoded)
+			}
+
+			output = append(output, r)
+		}
+
+		pos = i + 1
+	}
+
+	n := punyInitialN
+	i := 0
+	bias := punyInitialBias
+
+	for pos < len(encoded) {
+		oldI := i
+		w := 1
+
+		for k := punyBase; ; k += punyBase {
+			if pos >= len(encoded) {
+				return "", fmt.Errorf("%q is not valid punycode", encoded)
+			}
+
+			c := encoded[pos]
+			pos++
+			var digit int
+
+			switch {
+			case c >= 'a' && c <= 'z':
+				digit = int(c - 'a')
+			case c >= 'A' && c <= 'Z':
+				digit = int(c - 'A')
+			case c >= '0' && c <= '9':
+				digit = int(c-'0') + 26
+			default:
+				return "", fmt.Errorf("%q is not valid punycode", encoded)
+			}
+
+			i += digit * w
+			t := punyThreshold(k, bias)
+
+			if digit < t {
+				break
+			}
+
+			w *= punyBase - t
+
+			if i > utf8.MaxRune || w > utf8.MaxRune {
+				return "", fmt.Errorf("%q is not valid punycode", encoded)
+			}
+		}
+
+		bias = punyAdapt(i-oldI, len(output)+1, oldI == 0)
+		n += i / (len(output) + 1)
+		i %= len(output) + 1
+
+		if n > utf8.MaxRune {
+			return "", fmt.Errorf("%q is not valid punycode", encoded)
+		}
+
+		output = append(output[:i], append([]rune{rune(n)}, output[i:]...)...)
+		i++
+	}
+
+	return string(output), nil
+}
@@ -0,0 +1,60 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestPunycode(t *testing.T) {
+	cases := []struct {
+		unicode string
+		ascii   string
+	}{
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"münchen.de.", "xn--mnchen-3ya.de."},
+		{"例え.jp", "xn--r8jz45g.jp"},
+		{"www.example.com", "www.example.com"},
+		{"☃", "xn--n3h"},
+		// Samples from RFC 3492 section 7.1, lowercased
+		{"ليهمابتكلموشعربي؟", "xn--egbpdaj6bu4bxfgehfvwxn"},
+		{"他们为什么不说中文", "xn--ihqwcrb4cv8a8dqg056pqjye"},
+		{"3年b組金八先生", "xn--3b-ww4c5e180e575a65lsy2b"},
+		{"porquénopuedensimplementehablarenespañol", "xn--porqunopuedensimplementehablarenespaol-fmd56a"},
+	}
+
+	for _, c := range cases {
+		if ascii := ASCIIName(c.unicode); ascii != c.ascii {
+			t.Errorf("Expected '%s' but got '%s'", c.ascii, ascii)
+		}
+
+		if unicode := UnicodeName(c.ascii); unicode != c.unicode {
+			t.Errorf("Expected '%s' but got '%s'", c.unicode, unicode)
+		}
+	}
+
+	if name := UnicodeName("xn--!!.example"); name != "xn--!!.example" {
+		t.Errorf("Expected invalid A-labels to be left alone but got '%s'", name)
+	}
+}
+
+func TestCheckComposed(t *testing.T) {
+	for _, name := range []string{"bücher.example", "www", "例え.jp", "*.münchen"} {
+		if err := CheckComposed(name); err != nil {
+			t.Errorf("Unexpected error %v for '%s'", err, name)
+		}
+	}
+
+	// "bücher" with the umlaut as a combining mark, which would encode as xn--bucher-xyd
+	for _, name := range []string{"bu\u0308cher.example", "www.bu\u0308cher"} {
+		if err := CheckComposed(name); err == nil {
+			t.Errorf("Expected an error for '%s'", name)
+		}
+	}
+
+	_, err := FromLibdns(libdns.Record{Type: "A", Name: "bu\u0308cher", Value: "192.0.2.1"}, Options{})
+
+	if err == nil {
+		t.Errorf("Expected FromLibdns to reject a decomposed name")
+	}
+}
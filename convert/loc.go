@@ -0,0 +1,79 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Checks that `value` is LOC data in zone file form (RFC 1876):
+//
+//	d1 [m1 [s1]] {"N"|"S"} d2 [m2 [s2]] {"E"|"W"} alt["m"] [siz["m"] [hp["m"] [vp["m"]]]]
+//
+// and returns it with its fields separated by single spaces.
+func formatLOC(value string) (string, error) {
+	fields := strings.Fields(value)
+	rest, err := parseLOCCoordinate(fields, 90, "N", "S")
+
+	if err != nil {
+		return "", fmt.Errorf("invalid latitude in %q: %w", value, err)
+	}
+
+	rest, err = parseLOCCoordinate(rest, 180, "E", "W")
+
+	if err != nil {
+		return "", fmt.Errorf("invalid longitude in %q: %w", value, err)
+	}
+
+	if len(rest) < 1 || len(rest) > 4 {
+		return "", fmt.Errorf("%q must have an altitude and at most three precision values", value)
+	}
+
+	for i, field := range rest {
+		meters, err := strconv.ParseFloat(strings.TrimSuffix(field, "m"), 64)
+
+		if err != nil || (i > 0 && meters < 0) {
+			return "", fmt.Errorf("%q is not a valid distance in meters", field)
+		}
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// Parses the degrees, optional minutes and seconds, and hemisphere at the start of `fields`,
+// returning the remaining fields.
+func parseLOCCoordinate(fields []string, maxDegrees int, positive string, negative string) ([]string, error) {
+	for i, field := range fields {
+		if field == positive || field == negative {
+			if i == 0 || i > 3 {
+				return nil, fmt.Errorf("expected 1 to 3 values before %s", field)
+			}
+
+			degrees, err := strconv.Atoi(fields[0])
+
+			if err != nil || degrees < 0 || degrees > maxDegrees {
+				return nil, fmt.Errorf("degrees %q must be a number from 0 to %d", fields[0], maxDegrees)
+			}
+
+			if i > 1 {
+				minutes, err := strconv.Atoi(fields[1])
+
+				if err != nil || minutes < 0 || minutes > 59 {
+					return nil, fmt.Errorf("minutes %q must be a number from 0 to 59", fields[1])
+				}
+			}
+
+			if i > 2 {
+				seconds, err := strconv.ParseFloat(fields[2], 64)
+
+				if err != nil || seconds < 0 || seconds >= 60 {
+					return nil, fmt.Errorf("seconds %q must be a number from 0 to 59.999", fields[2])
+				}
+			}
+
+			return fields[i+1:], nil
+		}
+	}
+
+	return nil, fmt.Errorf("missing %s or %s", positive, negative)
+}
@@ -0,0 +1,34 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestLOCParameters(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{"52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m", "52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m"},
+		{"42  21 S  71 W  -24m", "42 21 S 71 W -24m"},
+	}
+
+	for _, c := range cases {
+		record := libdns.Record{Type: "LOC", Name: "", Value: c.value}
+		data := mustFromLibdns(t, record, Options{}).Data
+
+		if data != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, data)
+		}
+	}
+
+	for _, value := range []string{"52 22 N 4 53 E", "91 N 4 E 0m", "52 60 N 4 E 0m", "52 N 181 E 0m", "52 N 4 E 0m -1m", "52 22 23 1 N 4 E 0m"} {
+		record := libdns.Record{Type: "LOC", Name: "", Value: value}
+
+		if _, err := FromLibdns(record, Options{}); err == nil {
+			t.Errorf("%q: Expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package convert
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/libdns/libdns"
+)
+
+// Records read from NFSN carry metadata that libdns.Record has no field for in their ID, which
+// libdns reserves for provider-specific metadata. It's encoded as a URL query string, e.g.
+// "data=192.0.2.1&scope=system".
+const (
+	scopeMetadataKey = "scope"
+	dataMetadataKey  = "data"
+	auxMetadataKey   = "aux"
+)
+
+// Returns the metadata to store in the ID of the libdns.Record for `rr`.
+func (rr RR) metadata() url.Values {
+	metadata := url.Values{}
+
+	if rr.Scope != "" {
+		metadata.Set(scopeMetadataKey, rr.Scope)
+	}
+
+	metadata.Set(dataMetadataKey, rr.Data)
+
+	if rr.Aux != 0 {
+		metadata.Set(auxMetadataKey, strconv.Itoa(rr.Aux))
+	}
+
+	return metadata
+}
+
+// Returns the metadata stored in `record`'s ID.
+func recordMetadata(record libdns.Record) url.Values {
+	metadata, err := url.ParseQuery(record.ID)
+
+	if err != nil {
+		return url.Values{}
+	}
+
+	return metadata
+}
+
+// Scope returns the NFSN scope of a record read from NFSN: "system" for records managed by NFSN
+// itself, which members can't edit, and usually "member" otherwise. Returns an empty string for
+// records that didn't come from NFSN.
+func Scope(record libdns.Record) string {
+	return recordMetadata(record).Get(scopeMetadataKey)
+}
+
+// Returns the data string NFSN reported for `record` if it was read from NFSN and hasn't been
+// changed since. Data is only reused for records without an `aux` value; for the others, such as
+// MX records, NFSN reports the priority separately but expects it in the data when writing.
+func rawData(record libdns.Record) (string, bool) {
+	metadata := recordMetadata(record)
+
+	if !metadata.Has(dataMetadataKey) || metadata.Has(auxMetadataKey) {
+		return "", false
+	}
+
+	rrData := metadata.Get(dataMetadataKey)
+	original, err := RR{Name: record.Name, Type: record.Type, Data: rrData}.Record()
+
+	if err != nil || original.Value != record.Value || original.Priority != record.Priority || original.Weight != record.Weight {
+		return "", false
+	}
+
+	return rrData, true
+}
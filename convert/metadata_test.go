@@ -0,0 +1,41 @@
+package convert
+
+import (
+	"testing"
+)
+
+func TestRawDataRoundTrip(t *testing.T) {
+	cases := []struct {
+		nRecord  RR
+		expected string
+	}{
+		// Read back canonicalized, but deleted as stored
+		{RR{Name: "www", Type: "AAAA", Data: "2001:0DB8:0:0::0001", TTL: 3600}, "2001:0DB8:0:0::0001"},
+		{RR{Name: "", Type: "CAA", Data: `issue "letsencrypt.org"`, TTL: 3600}, `issue "letsencrypt.org"`},
+		// Priority is reported in aux, so the data is rebuilt
+		{RR{Name: "", Type: "MX", Data: "mail.example.com.", TTL: 3600, Aux: 10}, "10 mail.example.com."},
+	}
+
+	for _, c := range cases {
+		record, err := c.nRecord.Record()
+
+		if err != nil {
+			t.Fatalf("%s: Unexpected error %v", c.nRecord.Data, err)
+		}
+
+		data := mustFromLibdns(t, record, Options{}).Data
+
+		if data != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, data)
+		}
+	}
+
+	// Changing the record discards the original data
+	record, _ := RR{Name: "www", Type: "AAAA", Data: "2001:0DB8:0:0::0001", TTL: 3600}.Record()
+	record.Value = "2001:db8::2"
+	data := mustFromLibdns(t, record, Options{}).Data
+
+	if data != "2001:db8::2" {
+		t.Errorf("Expected '2001:db8::2' but got '%s'", data)
+	}
+}
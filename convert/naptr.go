@@ -0,0 +1,43 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Checks that `value` is NAPTR data without the order and preference, `flags service regexp
+// replacement`, and returns it with the flags, service, and regexp quoted and the replacement
+// qualified (see `qualifyTarget`). Flags must be alphanumeric (RFC 3403).
+func formatNAPTR(value string) (string, error) {
+	fields, err := splitQuotedFields(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(fields) != 4 {
+		return "", fmt.Errorf("%q is not in the form 'flags service regexp replacement'", value)
+	}
+
+	for i := 0; i < 3; i++ {
+		if len(fields[i]) < 2 || !strings.HasPrefix(fields[i], `"`) || !strings.HasSuffix(fields[i], `"`) {
+			fields[i] = strconv.Quote(fields[i])
+		}
+	}
+
+	flags := strings.Trim(fields[0], `"`)
+
+	if strings.IndexFunc(flags, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) >= 0 {
+		return "", fmt.Errorf("flags %q must be alphanumeric", flags)
+	}
+
+	if fields[2] != `""` && fields[3] != "." {
+		return "", fmt.Errorf("only one of regexp and replacement may be set")
+	}
+
+	fields[3] = qualifyTarget(fields[3])
+	return strings.Join(fields, " "), nil
+}
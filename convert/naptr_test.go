@@ -0,0 +1,62 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestNAPTRRecord(t *testing.T) {
+	cases := []RR{
+		{Name: "", Type: "NAPTR", Data: `100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`, TTL: 3600},
+		{Name: "", Type: "NAPTR", Data: `10 "S" "SIP+D2U" "" _sip._udp.example.com.`, TTL: 3600, Aux: 100},
+	}
+
+	for _, c := range cases {
+		record, err := c.Record()
+
+		if err != nil {
+			t.Fatalf("%s: Unexpected error %v", c.Data, err)
+		}
+
+		expected := `"S" "SIP+D2U" "" _sip._udp.example.com.`
+
+		if record.Priority != 100 || record.Weight != 10 || record.Value != expected {
+			t.Errorf("%s: Unexpected record %+v", c.Data, record)
+		}
+	}
+
+	_, err := RR{Name: "", Type: "NAPTR", Data: `"S" "SIP+D2U"`, TTL: 3600}.Record()
+
+	if err == nil {
+		t.Errorf("Expected an error for malformed NAPTR data")
+	}
+}
+
+func TestNAPTRParameters(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{`"S" "SIP+D2U" "" _sip._udp.example.com.`, `100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`},
+		{`U E2U+sip "!^.*$!sip:info@example.com!" .`, `100 10 "U" "E2U+sip" "!^.*$!sip:info@example.com!" .`},
+		{`"S" "SIP+D2T" "" _sip._tcp.example.com`, `100 10 "S" "SIP+D2T" "" _sip._tcp.example.com.`},
+	}
+
+	for _, c := range cases {
+		record := libdns.Record{Type: "NAPTR", Name: "", Value: c.value, Priority: 100, Weight: 10}
+		data := mustFromLibdns(t, record, Options{}).Data
+
+		if data != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, data)
+		}
+	}
+
+	for _, value := range []string{`"S" "SIP+D2U" ""`, `"S+" "SIP+D2U" "" .`, `"U" "E2U+sip" "!^.*$!sip:info@example.com!" example.com.`, `"S" "SIP`} {
+		record := libdns.Record{Type: "NAPTR", Name: "", Value: value}
+
+		if _, err := FromLibdns(record, Options{}); err == nil {
+			t.Errorf("%q: Expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// Normalize returns `record` in the canonical form NFSN stores it in, so that a record a caller
+// intends to write can be compared with one read from NFSN (see `Equal`):
+//
+//   - the name and type are lowercased and uppercased respectively, "@" becomes "", and Unicode
+//     names are converted to punycode
+//   - addresses are in canonical form, and hostname targets, including those of SRV, NAPTR, HTTPS,
+//     and SVCB records, are lowercased and fully qualified as they are by `FromLibdns`
+//   - quoted TXT values are unquoted and joined, as they are when read
+//   - TTLs are raised to NFSN's default minimum of 3 minutes (see `MinimumTTL`); zones may allow
+//     lower TTLs, so callers that know the zone's minimum should apply it first
+//   - the ID, which carries NFSN-specific metadata, is cleared
+//
+// Values that can't be parsed are left as they are.
+func Normalize(record libdns.Record) libdns.Record {
+	record.ID = ""
+	record.Type = strings.ToUpper(record.Type)
+	record.Name = strings.ToLower(ASCIIName(record.Name))
+
+	if record.Name == "@" {
+		record.Name = ""
+	}
+
+	switch record.Type {
+	case "A", "AAAA":
+		record.Value = canonicalAddress(record.Value)
+	case "ALIAS", "CNAME", "MX", "NS", "PTR":
+		record.Value = strings.ToLower(qualifyTarget(record.Value))
+	case "SRV":
+		if value, err := formatSRV(record.Name, record.Value); err == nil {
+			record.Value = lowerLastField(value)
+		}
+	case "NAPTR":
+		if value, err := formatNAPTR(record.Value); err == nil {
+			record.Value = lowerLastField(value)
+		}
+	case "TXT":
+		record.Value = joinTXT(record.Value)
+	case "CAA":
+		if value, err := formatCAA(record.Value); err == nil {
+			record.Value = value
+		}
+	case "HTTPS", "SVCB":
+		if value, err := formatServiceBinding(record.Value); err == nil {
+			target, params, _ := strings.Cut(value, " ")
+			record.Value = strings.TrimSpace(strings.ToLower(target) + " " + params)
+		}
+	}
+
+	if record.TTL < MinimumTTL {
+		record.TTL = MinimumTTL
+	}
+
+	return record
+}
+
+// Equal returns true if `a` and `b` are the same record once normalized (see `Normalize`).
+func Equal(a libdns.Record, b libdns.Record) bool {
+	return Normalize(a) == Normalize(b)
+}
+
+// Lowercases the last space-separated field of `value`, a hostname target.
+func lowerLastField(value string) string {
+	i := strings.LastIndex(value, " ")
+	return value[:i+1] + strings.ToLower(value[i+1:])
+}
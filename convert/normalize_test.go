@@ -0,0 +1,68 @@
+package convert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		a     libdns.Record
+		b     libdns.Record
+		equal bool
+	}{
+		{
+			libdns.Record{Type: "aaaa", Name: "WWW", Value: "2001:0DB8::0001", TTL: time.Minute},
+			libdns.Record{Type: "AAAA", Name: "www", Value: "2001:db8::1", TTL: 3 * time.Minute, ID: "scope=member"},
+			true,
+		},
+		{
+			libdns.Record{Type: "CNAME", Name: "@", Value: "Target.Example.NET"},
+			libdns.Record{Type: "CNAME", Name: "", Value: "target.example.net."},
+			true,
+		},
+		{
+			libdns.Record{Type: "TXT", Name: "_dmarc", Value: `"v=DMARC1; " "p=none"`},
+			libdns.Record{Type: "TXT", Name: "_dmarc", Value: "v=DMARC1; p=none"},
+			true,
+		},
+		{
+			libdns.Record{Type: "CAA", Name: "", Value: "0 issue letsencrypt.org"},
+			libdns.Record{Type: "CAA", Name: "", Value: `0 issue "letsencrypt.org"`},
+			true,
+		},
+		{
+			libdns.Record{Type: "MX", Name: "", Value: "mail.example.com.", Priority: 10},
+			libdns.Record{Type: "MX", Name: "", Value: "mail.example.com.", Priority: 20},
+			false,
+		},
+		{
+			libdns.Record{Type: "SRV", Name: "_sip._tcp", Value: "5060 SIP.example.com", Priority: 10, Weight: 5},
+			libdns.Record{Type: "SRV", Name: "_sip._tcp", Value: "5060 sip.example.com.", Priority: 10, Weight: 5},
+			true,
+		},
+		{
+			libdns.Record{Type: "NAPTR", Name: "", Value: `"U" "E2U+sip" "" SIP.example.com`, Priority: 100, Weight: 10},
+			libdns.Record{Type: "NAPTR", Name: "", Value: `"U" "E2U+sip" "" sip.example.com.`, Priority: 100, Weight: 10},
+			true,
+		},
+		{
+			libdns.Record{Type: "HTTPS", Name: "", Value: "CDN.example.com alpn=h2", Priority: 1},
+			libdns.Record{Type: "HTTPS", Name: "", Value: "cdn.example.com. alpn=h2", Priority: 1},
+			true,
+		},
+		{
+			libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: time.Hour},
+			libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 2 * time.Hour},
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		if Equal(c.a, c.b) != c.equal {
+			t.Errorf("Expected Equal(%+v, %+v) to be %v", c.a, c.b, c.equal)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Checks that `name` is an SRV owner name, "_service._proto" optionally followed by any number of
+// labels (e.g. "_sip._tcp.eu.voice"), and that `value` is "port target". Returns the value with the
+// target qualified (see `qualifyTarget`).
+func formatSRV(name string, value string) (string, error) {
+	labels := strings.Split(name, ".")
+
+	if len(labels) < 2 || len(labels[0]) < 2 || len(labels[1]) < 2 || labels[0][0] != '_' || labels[1][0] != '_' {
+		return "", fmt.Errorf("name %q is not in the form '_service._proto[.name]'", name)
+	}
+
+	parts := strings.Fields(value)
+
+	if len(parts) != 2 {
+		return "", fmt.Errorf("%q is not in the form 'port target'", value)
+	}
+
+	if _, err := strconv.ParseUint(parts[0], 10, 16); err != nil {
+		return "", fmt.Errorf("port %q must be a number from 0 to 65535", parts[0])
+	}
+
+	return parts[0] + " " + qualifyTarget(parts[1]), nil
+}
@@ -0,0 +1,37 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestSRVOwnerNames(t *testing.T) {
+	for _, name := range []string{"_sip._tcp", "_sip._tcp.voice", "_sip._tcp.eu.voice", "_ldap._tcp.dc._msdcs.corp"} {
+		record := libdns.Record{Type: "SRV", Name: name, Value: "5060 sip.example.com", Priority: 10, Weight: 5}
+		rr := mustFromLibdns(t, record, Options{})
+
+		if rr.Name != name || rr.Data != "10 5 5060 sip.example.com." {
+			t.Errorf("%s: Unexpected record %+v", name, rr)
+		}
+
+		// Round trip back through the NFSN representation
+		roundTrip, err := RR{Name: rr.Name, Type: "SRV", Data: "5 5060 sip.example.com.", TTL: 3600, Aux: 10}.Record()
+
+		if err != nil {
+			t.Fatalf("%s: Unexpected error %v", name, err)
+		}
+
+		if roundTrip.Name != name || roundTrip.Value != "5060 sip.example.com." || roundTrip.Priority != 10 || roundTrip.Weight != 5 {
+			t.Errorf("%s: Unexpected record %+v", name, roundTrip)
+		}
+	}
+
+	for _, name := range []string{"sip", "_sip", "_sip.tcp", "_._tcp"} {
+		record := libdns.Record{Type: "SRV", Name: name, Value: "5060 sip.example.com."}
+
+		if _, err := FromLibdns(record, Options{}); err == nil {
+			t.Errorf("%s: Expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package convert
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Length in hex characters of each SSHFP fingerprint type's digest
+var sshfpFingerprintLengths = map[uint64]int{
+	1: 40, // SHA-1
+	2: 64, // SHA-256
+}
+
+// Checks that `value` is SSHFP data in zone file form, "algorithm type fingerprint", and returns it
+// with the fingerprint in lowercase. The fingerprint must be hex of the length its type requires
+// (RFC 4255, RFC 6594).
+func formatSSHFP(value string) (string, error) {
+	parts := strings.Fields(value)
+
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%q is not in the form 'algorithm type fingerprint'", value)
+	}
+
+	algorithm, fpType, fingerprint := parts[0], parts[1], strings.ToLower(parts[2])
+
+	if _, err := strconv.ParseUint(algorithm, 10, 8); err != nil {
+		return "", fmt.Errorf("algorithm %q must be a number from 0 to 255", algorithm)
+	}
+
+	typeNum, err := strconv.ParseUint(fpType, 10, 8)
+
+	if err != nil {
+		return "", fmt.Errorf("fingerprint type %q must be a number from 0 to 255", fpType)
+	}
+
+	if _, err := hex.DecodeString(fingerprint); err != nil {
+		return "", fmt.Errorf("fingerprint %q must be hexadecimal", fingerprint)
+	}
+
+	if length, ok := sshfpFingerprintLengths[typeNum]; ok && len(fingerprint) != length {
+		return "", fmt.Errorf("fingerprint of type %d must be %d hex characters, not %d", typeNum, length, len(fingerprint))
+	}
+
+	return fmt.Sprintf("%s %s %s", algorithm, fpType, fingerprint), nil
+}
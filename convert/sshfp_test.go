@@ -0,0 +1,41 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestSSHFPParameters(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{"4 2 9DBA83B6E3F0C4F3F1A5D0C3D5F0E2A1B4C7D8E9F0A1B2C3D4E5F60718293A4B", "4 2 9dba83b6e3f0c4f3f1a5d0c3d5f0e2a1b4c7d8e9f0a1b2c3d4e5f60718293a4b"},
+		{"1 1 dd465c09cfa51fb45020cc83316fff21b9ec74ac", "1 1 dd465c09cfa51fb45020cc83316fff21b9ec74ac"},
+	}
+
+	for _, c := range cases {
+		record := libdns.Record{Type: "SSHFP", Name: "host", Value: c.value}
+		data := mustFromLibdns(t, record, Options{}).Data
+
+		if data != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, data)
+		}
+
+		// NFSN returns the data as it was written
+		roundTrip, err := RR{Name: "host", Type: "SSHFP", Data: data, TTL: 3600}.Record()
+
+		if err != nil || roundTrip.Value != c.expected {
+			t.Errorf("Expected '%s' but got '%s', %v", c.expected, roundTrip.Value, err)
+		}
+	}
+
+	for _, value := range []string{"4 2", "x 2 abcd", "4 2 abcd", "4 1 zz465c09cfa51fb45020cc83316fff21b9ec74ac"} {
+		record := libdns.Record{Type: "SSHFP", Name: "host", Value: value}
+
+		if _, err := FromLibdns(record, Options{}); err == nil {
+			t.Errorf("%q: Expected an error", value)
+		}
+	}
+}
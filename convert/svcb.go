@@ -0,0 +1,80 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Checks that `value` is HTTPS or SVCB data without the priority, "target params", and returns it
+// with the target qualified (see `qualifyTarget`). Each param must be "key" or "key=value" with a
+// lowercase key, and no key may appear twice (RFC 9460). Quoted values may contain spaces.
+func formatServiceBinding(value string) (string, error) {
+	fields, err := splitQuotedFields(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%q is not in the form 'target params'", value)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, param := range fields[1:] {
+		key := strings.SplitN(param, "=", 2)[0]
+
+		if key == "" || strings.IndexFunc(key, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-')
+		}) >= 0 {
+			return "", fmt.Errorf("param key %q must be lowercase alphanumeric", key)
+		}
+
+		if seen[key] {
+			return "", fmt.Errorf("param %q appears more than once", key)
+		}
+
+		seen[key] = true
+	}
+
+	fields[0] = qualifyTarget(fields[0])
+	return strings.Join(fields, " "), nil
+}
+
+// Splits `value` on spaces that aren't inside double quotes.
+func splitQuotedFields(value string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	quoted := false
+	escaped := false
+
+	for _, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+
+			continue
+		}
+
+		field.WriteRune(r)
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", value)
+	}
+
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,35 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestServiceBindingParameters(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{`. alpn="h2,h3"`, `1 . alpn="h2,h3"`},
+		{`svc.example.net port=8443 no-default-alpn`, `1 svc.example.net. port=8443 no-default-alpn`},
+		{`svc  alpn=h2  ech="AEn+DQBF a"`, `1 svc alpn=h2 ech="AEn+DQBF a"`},
+	}
+
+	for _, c := range cases {
+		record := libdns.Record{Type: "SVCB", Name: "_8443._foo", Value: c.value, Priority: 1}
+		data := mustFromLibdns(t, record, Options{}).Data
+
+		if data != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, data)
+		}
+	}
+
+	for _, value := range []string{"", `. alpn=h2 alpn=h3`, `. ALPN=h2`, `. alpn="h2`} {
+		record := libdns.Record{Type: "HTTPS", Name: "", Value: value, Priority: 1}
+
+		if _, err := FromLibdns(record, Options{}); err == nil {
+			t.Errorf("%q: Expected an error", value)
+		}
+	}
+}
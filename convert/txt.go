@@ -0,0 +1,181 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The longest a single TXT character-string can be, in bytes
+const maxTXTStringLength = 255
+
+// The longest the RDATA of a record can be, in bytes. Each TXT character-string takes its length
+// plus a one byte length prefix.
+const maxRDataLength = 65535
+
+// Splits TXT record data into its character-strings. Data that starts with a double quote is
+// treated as one or more quoted strings (`"first" "second"`), in which a backslash escapes the
+// following character, or introduces a three digit decimal byte value (`\059`). Anything else is a
+// single unquoted string.
+func parseTXTStrings(value string) ([]string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		return []string{value}, nil
+	}
+
+	var strs []string
+	var current strings.Builder
+	inString := false
+	escaped := false
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		switch {
+		case escaped && isDecimalEscape(value[i:]):
+			b, _ := strconv.Atoi(value[i : i+3])
+			current.WriteByte(byte(b))
+			i += 2
+			escaped = false
+		case escaped:
+			current.WriteByte(c)
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case inString && c == '"':
+			strs = append(strs, current.String())
+			current.Reset()
+			inString = false
+		case inString:
+			current.WriteByte(c)
+		case c == '"':
+			inString = true
+		case c == ' ' || c == '\t':
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d outside of a quoted string", c, i)
+		}
+	}
+
+	if inString {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+
+	return strs, nil
+}
+
+// Returns true if `s` starts with three digits that form a byte value.
+func isDecimalEscape(s string) bool {
+	if len(s) < 3 || strings.IndexFunc(s[:3], func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return false
+	}
+
+	b, _ := strconv.Atoi(s[:3])
+	return b <= 255
+}
+
+// Checks that no character-string in the TXT record data is longer than 255 bytes, and that the
+// record as a whole fits in a DNS record.
+func validateTXT(value string) error {
+	strs, err := parseTXTStrings(value)
+
+	if err != nil {
+		return err
+	}
+
+	total := 0
+
+	for i, str := range strs {
+		if len(str) > maxTXTStringLength {
+			return fmt.Errorf("string %d of %d is %d bytes long, which exceeds the limit of %d bytes: %.32q...", i+1, len(strs), len(str), maxTXTStringLength, str)
+		}
+
+		total += len(str) + 1
+	}
+
+	if total > maxRDataLength {
+		return fmt.Errorf("data is %d bytes long, which exceeds the limit of %d bytes", total, maxRDataLength)
+	}
+
+	return nil
+}
+
+// Returns the TXT record data to send to NFSN for `value`. Unquoted values too long for a single
+// character-string are split into as many quoted strings as necessary, e.g. for DKIM keys, and
+// unquoted values containing characters NFSN would otherwise interpret (quotes, backslashes,
+// semicolons, or surrounding whitespace) are quoted and escaped. Quoted values are sent as they
+// are, since the caller has chosen how to split them.
+//
+// If `strict` is true, values with control characters or non-ASCII characters, which NFSN doesn't
+// reliably store as sent, are rejected.
+func formatTXT(value string, strict bool) (string, error) {
+	if !strings.HasPrefix(value, `"`) && (len(value) > maxTXTStringLength || needsTXTQuoting(value)) {
+		value = quoteTXTStrings(splitTXT(value))
+	}
+
+	err := validateTXT(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	if strict {
+		strs, _ := parseTXTStrings(value)
+
+		for _, str := range strs {
+			if i := strings.IndexFunc(str, func(r rune) bool { return r < 0x20 || r >= 0x7f }); i >= 0 {
+				r, _ := utf8.DecodeRuneInString(str[i:])
+				return "", fmt.Errorf("character %q at offset %d can't be stored reliably", r, i)
+			}
+		}
+	}
+
+	return value, nil
+}
+
+// Returns true if unquoted TXT data `value` would be interpreted differently by NFSN than the
+// literal text.
+func needsTXTQuoting(value string) bool {
+	return strings.ContainsAny(value, `"\;`) || strings.TrimSpace(value) != value
+}
+
+// Splits `value` into strings of at most 255 bytes, without splitting UTF-8 sequences.
+func splitTXT(value string) []string {
+	var strs []string
+
+	for len(value) > maxTXTStringLength {
+		end := maxTXTStringLength
+
+		for end > 0 && !utf8.RuneStart(value[end]) {
+			end--
+		}
+
+		strs = append(strs, value[:end])
+		value = value[end:]
+	}
+
+	return append(strs, value)
+}
+
+// Formats `strs` as space separated quoted strings, escaping quotes and backslashes.
+func quoteTXTStrings(strs []string) string {
+	quoted := make([]string, len(strs))
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	for i, str := range strs {
+		quoted[i] = `"` + escaper.Replace(str) + `"`
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// Returns the value of TXT record data read from NFSN: quoted strings are unquoted and joined, as
+// DNS clients do with the strings of a TXT record. Data that can't be parsed is returned as is.
+func joinTXT(data string) string {
+	strs, err := parseTXTStrings(data)
+
+	if err != nil {
+		return data
+	}
+
+	return strings.Join(strs, "")
+}
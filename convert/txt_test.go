@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestTXTStringLength(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	cases := []struct {
+		value string
+		valid bool
+	}{
+		{"v=spf1 -all", true},
+		{strings.Repeat("a", 255), true},
+		{long, true}, // Split automatically
+		{`"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("b", 45) + `"`, true},
+		{`"first" "` + long + `"`, false},
+		{`"unterminated`, false},
+	}
+
+	for _, c := range cases {
+		record := libdns.Record{Type: "TXT", Name: "_domainkey", Value: c.value}
+		_, err := FromLibdns(record, Options{})
+
+		if c.valid && err != nil {
+			t.Errorf("%.20q: Unexpected error %v", c.value, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%.20q: Expected an error", c.value)
+		}
+	}
+
+	record := libdns.Record{Type: "TXT", Name: "_domainkey", Value: `"first" "` + long + `"`}
+	_, err := FromLibdns(record, Options{})
+
+	if err == nil || !strings.Contains(err.Error(), "string 2 of 2 is 300 bytes long") {
+		t.Errorf("Expected the error to identify the long string but got %v", err)
+	}
+}
+
+func TestTXTSplitting(t *testing.T) {
+	dkim := "v=DKIM1; k=rsa; p=" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A", 12)
+	record := libdns.Record{Type: "TXT", Name: "default._domainkey", Value: dkim}
+	data := mustFromLibdns(t, record, Options{}).Data
+	strs, err := parseTXTStrings(data)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(strs) != 2 || len(strs[0]) != 255 {
+		t.Errorf("Expected a 255 byte string and the remainder but got %q", strs)
+	}
+
+	// Joined again when read
+	roundTrip, err := RR{Name: record.Name, Type: "TXT", Data: data, TTL: 3600}.Record()
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if roundTrip.Value != dkim {
+		t.Errorf("Expected '%s' but got '%s'", dkim, roundTrip.Value)
+	}
+
+	// Quotes and backslashes are escaped, and multi-byte characters aren't split
+	value := strings.Repeat("é", 128) + `"\`
+	data = mustFromLibdns(t, libdns.Record{Type: "TXT", Name: "", Value: value}, Options{}).Data
+	strs, err = parseTXTStrings(data)
+
+	if err != nil || len(strs) != 2 || strs[0] != strings.Repeat("é", 127) || strs[1] != `é"\` {
+		t.Errorf("Unexpected strings %q from '%s', %v", strs, data, err)
+	}
+}
+
+func TestTXTQuoting(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected string
+	}{
+		{"v=spf1 -all", "v=spf1 -all"},
+		{"v=DMARC1; p=none", `"v=DMARC1; p=none"`},
+		{`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{" padded ", `" padded "`},
+		{`"already quoted"`, `"already quoted"`},
+	}
+
+	for _, c := range cases {
+		record := libdns.Record{Type: "TXT", Name: "", Value: c.value}
+		data := mustFromLibdns(t, record, Options{}).Data
+
+		if data != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, data)
+		}
+
+		// Unquoted again when read
+		expected := strings.Trim(c.value, `"`)
+		roundTrip, _ := RR{Name: "", Type: "TXT", Data: data, TTL: 3600}.Record()
+
+		if roundTrip.Value != expected {
+			t.Errorf("Expected '%s' but got '%s'", expected, roundTrip.Value)
+		}
+	}
+
+	if value := joinTXT(`"a\059b\"c" "d"`); value != `a;b"cd` {
+		t.Errorf("Expected 'a;b\"cd' but got '%s'", value)
+	}
+
+	for _, value := range []string{"tab\there", "naïve", `"bell\007"`} {
+		record := libdns.Record{Type: "TXT", Name: "", Value: value}
+
+		if _, err := FromLibdns(record, Options{StrictTXT: true}); err == nil {
+			t.Errorf("%q: Expected an error in strict mode", value)
+		}
+
+		if _, err := FromLibdns(record, Options{}); err != nil {
+			t.Errorf("%q: Unexpected error %v", value, err)
+		}
+	}
+}
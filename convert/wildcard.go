@@ -0,0 +1,34 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WildcardName validates the use of `*` in `name` and returns the name NFSN should be sent. A
+// wildcard must be the whole of the leftmost label, e.g. "*" or "*.sub"; an asterisk anywhere else
+// is rejected. NFSN has no way to store a literal asterisk label, so a leftmost label escaped as
+// `\*` or `\042`, as zone file tools write it, is taken to mean the wildcard.
+func WildcardName(name string) (string, error) {
+	labels := strings.Split(name, ".")
+
+	if labels[0] == `\*` || labels[0] == `\042` {
+		labels[0] = "*"
+	}
+
+	for i, label := range labels {
+		if !strings.Contains(label, "*") {
+			continue
+		}
+
+		if i != 0 {
+			return "", fmt.Errorf("Name %q has a wildcard that isn't the leftmost label", name)
+		}
+
+		if label != "*" {
+			return "", fmt.Errorf("Name %q has a wildcard that isn't a whole label", name)
+		}
+	}
+
+	return strings.Join(labels, "."), nil
+}
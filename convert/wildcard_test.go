@@ -0,0 +1,44 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestWildcardName(t *testing.T) {
+	valid := map[string]string{
+		"*":              "*",
+		"*.sub":          "*.sub",
+		`\*.sub`:         "*.sub",
+		`\042`:           "*",
+		"*.example.com.": "*.example.com.",
+		"www":            "www",
+	}
+
+	for name, expected := range valid {
+		actual, err := WildcardName(name)
+
+		if err != nil {
+			t.Errorf("%s: Unexpected error %v", name, err)
+		} else if actual != expected {
+			t.Errorf("Expected '%s' but got '%s'", expected, actual)
+		}
+	}
+
+	for _, name := range []string{"sub.*", "a.*.b", "*www", "w*w.sub", "**", `\*\*`} {
+		if _, err := WildcardName(name); err == nil {
+			t.Errorf("%s: Expected an error", name)
+		}
+	}
+
+	rr := mustFromLibdns(t, libdns.Record{Type: "A", Name: `\*.sub`, Value: "192.0.2.1"}, Options{})
+
+	if rr.Name != "*.sub" {
+		t.Errorf("Expected '*.sub' but got '%s'", rr.Name)
+	}
+
+	if _, err := FromLibdns(libdns.Record{Type: "A", Name: "sub.*", Value: "192.0.2.1"}, Options{}); err == nil {
+		t.Errorf("Expected an error for a misplaced wildcard")
+	}
+}
@@ -0,0 +1,178 @@
+package nfsn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Environment variables consulted for credentials that aren't configured on the Provider
+const loginEnvVar = "NFSN_LOGIN"
+const apiKeyEnvVar = "NFSN_API_KEY"
+
+// CredentialSource supplies the credentials to authenticate API requests with. It is consulted for
+// every request, so implementations that fetch credentials remotely should cache them.
+type CredentialSource interface {
+	// Credentials returns the NFSN member login and API key to use.
+	Credentials(ctx context.Context) (login string, apiKey string, err error)
+}
+
+// Returns the login and API key to authenticate with. If there's a CredentialSource it alone is
+// used. Otherwise the API key is taken from SetAPIKeyBytes, in which case the returned key is empty,
+// then APIKey, then APIKeyFile, then the OS keyring, and
+// either credential falls back to its environment variable if it isn't otherwise configured,
+// unless `DisableEnvCredentials` is set.
+func (p *Provider) credentials(ctx context.Context) (string, string, error) {
+	if p.CredentialSource != nil {
+		return p.CredentialSource.Credentials(ctx)
+	}
+
+	login := p.Login
+	apiKey := p.APIKey
+
+	if login == "" && !p.DisableEnvCredentials {
+		login = os.Getenv(loginEnvVar)
+	}
+
+	// The key is only available as bytes, see `writeAPIKeyBytes`
+	if p.hasAPIKeyBytes() {
+		return login, "", nil
+	}
+
+	if apiKey == "" && p.APIKeyFile != "" {
+		var err error
+		apiKey, err = p.readAPIKeyFile()
+
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if apiKey == "" && p.KeyringService != "" {
+		var err error
+		apiKey, err = p.readKeyringAPIKey(ctx, login)
+
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if apiKey == "" && !p.DisableEnvCredentials {
+		apiKey = os.Getenv(apiKeyEnvVar)
+	}
+
+	return login, apiKey, nil
+}
+
+// Returns the API key from APIKeyFile, reading it if it hasn't been read already.
+func (p *Provider) readAPIKeyFile() (string, error) {
+	p.fileAPIKeyMtx.Lock()
+	defer p.fileAPIKeyMtx.Unlock()
+
+	if p.fileAPIKey != "" {
+		return p.fileAPIKey, nil
+	}
+
+	contents, err := os.ReadFile(p.APIKeyFile)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to read API key file: %w", err)
+	}
+
+	p.fileAPIKey = strings.TrimSpace(string(contents))
+	return p.fileAPIKey, nil
+}
+
+// Discards the cached API key file contents so that the file is read again on next use. Returns
+// false if the API key doesn't come from a file.
+func (p *Provider) forgetAPIKeyFile() bool {
+	if p.APIKey != "" || p.APIKeyFile == "" {
+		return false
+	}
+
+	p.fileAPIKeyMtx.Lock()
+	defer p.fileAPIKeyMtx.Unlock()
+
+	p.fileAPIKey = ""
+	return true
+}
+
+// ValidateCredentials checks that NFSN accepts the Provider's credentials by making a cheap
+// authenticated request (reading the member's `accounts` property), so that bad credentials can be
+// reported at startup rather than when records are first changed. Returns nil if they're accepted.
+func (p *Provider) ValidateCredentials(ctx context.Context) error {
+	login, _, err := p.credentials(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if login == "" {
+		return fmt.Errorf("Failed to validate credentials: no login configured")
+	}
+
+	_, err = p.makeRequest(ctx, "GET", p.uriForMember(login, "accounts"), nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to validate credentials: %w", err)
+	}
+
+	return nil
+}
+
+// SetAPIKeyBytes sets the API key to a copy of `key`, taking precedence over APIKey. Unlike APIKey,
+// which is a string and so can't be erased from memory, a key set this way is never copied into a
+// string and is overwritten with zeros by ZeroCredentials. The caller may zero `key` once this
+// returns.
+func (p *Provider) SetAPIKeyBytes(key []byte) {
+	p.apiKeyBytesMtx.Lock()
+	defer p.apiKeyBytesMtx.Unlock()
+
+	zero(p.apiKeyBytes)
+	p.apiKeyBytes = append([]byte(nil), key...)
+}
+
+// ZeroCredentials discards the Provider's API key: a key set with SetAPIKeyBytes is overwritten
+// with zeros, and APIKey and any keys cached from APIKeyFile, the OS keyring or signed requests are
+// cleared. Strings can't be overwritten, so those may remain in memory until they are garbage
+// collected. Requests made afterwards fail unless another key is configured.
+func (p *Provider) ZeroCredentials() {
+	p.apiKeyBytesMtx.Lock()
+	zero(p.apiKeyBytes)
+	p.apiKeyBytes = nil
+	p.apiKeyBytesMtx.Unlock()
+
+	p.fileAPIKeyMtx.Lock()
+	p.fileAPIKey = ""
+	p.fileAPIKeyMtx.Unlock()
+
+	p.keyringAPIKeyMtx.Lock()
+	p.keyringAPIKey = ""
+	p.keyringAPIKeyMtx.Unlock()
+
+	p.APIKey = ""
+}
+
+func (p *Provider) hasAPIKeyBytes() bool {
+	p.apiKeyBytesMtx.Lock()
+	defer p.apiKeyBytesMtx.Unlock()
+
+	return len(p.apiKeyBytes) > 0
+}
+
+// Writes the key set with SetAPIKeyBytes to `w` without copying it.
+func (p *Provider) writeAPIKeyBytes(w io.Writer) {
+	p.apiKeyBytesMtx.Lock()
+	defer p.apiKeyBytesMtx.Unlock()
+
+	w.Write(p.apiKeyBytes)
+}
+
+// Overwrites `b` with zeros.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
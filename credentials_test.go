@@ -0,0 +1,296 @@
+package nfsn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	t.Setenv("NFSN_LOGIN", "testuser")
+	t.Setenv("NFSN_API_KEY", "p3kxmRKf9dk3l6ls")
+
+	req, err := http.NewRequest("GET", "https://api.nearlyfreespeech.net/site/example/getInfo", nil)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Same vector as TestGetAuthValue
+	p := Provider{}
+	authVal, err := p.innerGetAuthValue(req, time.Unix(1012121212, 0), "dkwo28Sile4jdXkw")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := "testuser;1012121212;dkwo28Sile4jdXkw;0fa8932e122d56e2f6d1550f9aab39c4aef8bfc4"
+
+	if authVal != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, authVal)
+	}
+
+	p = Provider{Login: "otheruser"}
+
+	if login, apiKey, _ := p.credentials(context.Background()); login != "otheruser" || apiKey != "p3kxmRKf9dk3l6ls" {
+		t.Errorf("Expected configured login and environment API key but got '%s', '%s'", login, apiKey)
+	}
+
+	p = Provider{DisableEnvCredentials: true}
+
+	if login, apiKey, _ := p.credentials(context.Background()); login != "" || apiKey != "" {
+		t.Errorf("Expected no credentials but got '%s', '%s'", login, apiKey)
+	}
+}
+
+func TestAPIKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "api_key.txt")
+	os.WriteFile(keyFile, []byte("oldkey\n"), 0600)
+	var keys []string
+
+	// Only accepts requests signed with "newkey"
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		authVal := r.Header.Get(authHeader)
+		parts := strings.Split(authVal, ";")
+		key := "oldkey"
+
+		req, _ := http.NewRequest(r.Method, r.URL.String(), nil)
+		expected, _ := (&Provider{Login: "testuser", APIKey: "newkey"}).innerGetAuthValue(req, time.Unix(mustAtoi(t, parts[1]), 0), parts[2])
+
+		if authVal == expected {
+			key = "newkey"
+		} else {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+
+		keys = append(keys, key)
+		w.Write([]byte("[]"))
+	})
+	p.APIKey = ""
+	p.APIKeyFile = keyFile
+
+	if _, apiKey, err := p.credentials(context.Background()); err != nil || apiKey != "oldkey" {
+		t.Fatalf("Expected 'oldkey' but got '%s' with error %v", apiKey, err)
+	}
+
+	// The key is rotated; the cached key is rejected and the file is read again
+	os.WriteFile(keyFile, []byte("newkey\n"), 0600)
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"oldkey", "newkey"}
+
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v but got %v", expected, keys)
+	}
+
+	p.APIKeyFile = filepath.Join(t.TempDir(), "missing.txt")
+	p.fileAPIKey = ""
+
+	if _, err = p.GetRecords(context.Background(), "example.com."); err == nil {
+		t.Errorf("Expected an error for a missing key file")
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int64 {
+	i, err := strconv.ParseInt(s, 10, 64)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return i
+}
+
+// Hands out a new key on each call
+type rotatingCredentials struct {
+	calls int
+}
+
+func (r *rotatingCredentials) Credentials(ctx context.Context) (string, string, error) {
+	r.calls++
+
+	if r.calls > 2 {
+		return "", "", errors.New("vault unavailable")
+	}
+
+	return "testuser", fmt.Sprintf("key%d", r.calls), nil
+}
+
+func TestCredentialSource(t *testing.T) {
+	var authVals []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		authVals = append(authVals, r.Header.Get(authHeader))
+		w.Write([]byte("[]"))
+	})
+	source := &rotatingCredentials{}
+	p.CredentialSource = source
+
+	for i := 0; i < 2; i++ {
+		_, err := p.GetRecords(context.Background(), "example.com.")
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if len(authVals) != 2 {
+		t.Fatalf("Expected 2 requests but got %d", len(authVals))
+	}
+
+	// Each request must be signed with the key current at the time
+	for i, authVal := range authVals {
+		parts := strings.Split(authVal, ";")
+		req, _ := http.NewRequest("POST", "/dns/example.com/listRRs", nil)
+		expected, _ := (&Provider{Login: "testuser", APIKey: fmt.Sprintf("key%d", i+1)}).innerGetAuthValue(req, time.Unix(mustAtoi(t, parts[1]), 0), parts[2])
+
+		if authVal != expected {
+			t.Errorf("Expected '%s' but got '%s'", expected, authVal)
+		}
+	}
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err == nil || !strings.Contains(err.Error(), "vault unavailable") {
+		t.Errorf("Expected the credential source error but got %v", err)
+	}
+}
+
+func TestKeyringService(t *testing.T) {
+	original := keyringLookup
+	t.Cleanup(func() { keyringLookup = original })
+
+	var lookups []string
+	keyringLookup = func(ctx context.Context, service string, account string) (string, error) {
+		lookups = append(lookups, service+"/"+account)
+		return "p3kxmRKf9dk3l6ls", nil
+	}
+
+	p := Provider{Login: "testuser", KeyringService: "nfsn", DisableEnvCredentials: true}
+
+	for i := 0; i < 2; i++ {
+		if login, apiKey, err := p.credentials(context.Background()); err != nil || login != "testuser" || apiKey != "p3kxmRKf9dk3l6ls" {
+			t.Errorf("Expected keyring credentials but got '%s', '%s', %v", login, apiKey, err)
+		}
+	}
+
+	if !reflect.DeepEqual(lookups, []string{"nfsn/testuser"}) {
+		t.Errorf("Expected a single lookup but got %v", lookups)
+	}
+
+	keyringLookup = func(ctx context.Context, service string, account string) (string, error) {
+		return "", ErrKeyringUnsupported
+	}
+	p = Provider{Login: "testuser", KeyringService: "nfsn"}
+
+	if _, _, err := p.credentials(context.Background()); !errors.Is(err, ErrKeyringUnsupported) {
+		t.Errorf("Expected ErrKeyringUnsupported but got %v", err)
+	}
+
+	p = Provider{KeyringService: "nfsn", DisableEnvCredentials: true}
+
+	if _, _, err := p.credentials(context.Background()); err == nil {
+		t.Errorf("Expected an error without a login")
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	var path string
+	status := http.StatusOK
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(status)
+		w.Write([]byte(`["A1B2-C3D4E5F6"]`))
+	})
+
+	if err := p.ValidateCredentials(context.Background()); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if path != "/member/testuser/accounts" {
+		t.Errorf("Expected '/member/testuser/accounts' but got '%s'", path)
+	}
+
+	status = http.StatusUnauthorized
+
+	if err := p.ValidateCredentials(context.Background()); err == nil {
+		t.Errorf("Expected an error for rejected credentials")
+	}
+
+	p.Login = ""
+	p.DisableEnvCredentials = true
+
+	if err := p.ValidateCredentials(context.Background()); err == nil {
+		t.Errorf("Expected an error without a login")
+	}
+}
+
+func TestAPIKeyBytes(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://api.nearlyfreespeech.net/site/example/getInfo", nil)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	key := []byte("p3kxmRKf9dk3l6ls")
+	p := Provider{Login: "testuser", APIKey: "wrongkey", DisableEnvCredentials: true}
+	p.SetAPIKeyBytes(key)
+	zero(key)
+
+	// Same vector as TestGetAuthValue
+	authVal, err := p.innerGetAuthValue(req, time.Unix(1012121212, 0), "dkwo28Sile4jdXkw")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := "testuser;1012121212;dkwo28Sile4jdXkw;0fa8932e122d56e2f6d1550f9aab39c4aef8bfc4"
+
+	if authVal != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, authVal)
+	}
+
+	stored := p.apiKeyBytes
+	p.ZeroCredentials()
+
+	if !bytes.Equal(stored, make([]byte, len(stored))) {
+		t.Errorf("Expected the key to be zeroed but got %v", stored)
+	}
+
+	if _, apiKey, _ := p.credentials(context.Background()); apiKey != "" || p.hasAPIKeyBytes() {
+		t.Errorf("Expected no API key but got '%s'", apiKey)
+	}
+}
+
+func TestDecodeCredentialBlob(t *testing.T) {
+	cases := []struct {
+		blob     []byte
+		expected string
+	}{
+		// As stored by cmdkey
+		{[]byte("p\x003\x00k\x00x\x00"), "p3kx"},
+		// As stored by tools that write the bytes as is
+		{[]byte("p3kxmRKf"), "p3kxmRKf"},
+		{[]byte("p3k"), "p3k"},
+	}
+
+	for _, c := range cases {
+		if decoded := decodeCredentialBlob(c.blob); decoded != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, decoded)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package nfsn
+
+// Windows Credential Manager only exists on Windows.
+func readCredentialManager(target string) (string, error) {
+	return "", ErrKeyringUnsupported
+}
@@ -0,0 +1,64 @@
+package nfsn
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32     = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead = advapi32.NewProc("CredReadW")
+	procCredFree = advapi32.NewProc("CredFree")
+)
+
+// CRED_TYPE_GENERIC
+const credTypeGeneric = 1
+
+// ERROR_NOT_FOUND
+const errorNotFound = syscall.Errno(1168)
+
+// The CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// Reads the generic credential `target` from Windows Credential Manager with CredReadW. Returns ""
+// if there is no such credential.
+func readCredentialManager(target string) (string, error) {
+	targetPtr, err := syscall.UTF16PtrFromString(target)
+
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ok, _, err := procCredRead.Call(uintptr(unsafe.Pointer(targetPtr)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+
+	if ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+
+	return decodeCredentialBlob(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
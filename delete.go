@@ -0,0 +1,191 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/nfsn/convert"
+)
+
+// DeleteRecordsWhere fetches the zone and deletes every record for which `pred` returns true. It
+// returns the records that were deleted. In the case where only some records are deleted returns
+// both the records that were deleted and an error.
+//
+// Records managed by NFSN (those with the "system" scope) are never passed to `pred` unless
+// `AllowSystemRecordDeletion` is set. Records that can't be parsed are skipped and reported in a
+// `RecordParseErrors` once the deletions are done. Exactly the matching records are removed; unlike
+// with DeleteRecords, a record with an empty value doesn't stand for its whole set.
+func (p *Provider) DeleteRecordsWhere(ctx context.Context, zone string, pred func(libdns.Record) bool) ([]libdns.Record, error) {
+	nRecords, err := p.listRecords(ctx, zone, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []libdns.Record
+	var ops []Operation
+	var parseErrors RecordParseErrors
+
+	for _, nRecord := range nRecords {
+		if nRecord.Scope == systemScope && !p.AllowSystemRecordDeletion {
+			continue
+		}
+
+		record, parseErr := p.toLibdnsRecord(nRecord)
+
+		if parseErr != nil {
+			parseErrors = append(parseErrors, *parseErr)
+			continue
+		}
+
+		if !pred(record) {
+			continue
+		}
+
+		// As in DeleteRecords, nothing is deleted if any match can't be
+		err = checkReadOnly(record)
+
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, record)
+		ops = append(ops, p.removeOperation(ctx, nRecord, record, true))
+	}
+
+	var deleted []libdns.Record
+
+	if len(ops) > 0 {
+		deleted, err = p.applyOperations(p.withContinueOnError(ctx), zone, ops)
+
+		if err != nil {
+			return deleted, orderBatchError(err, matches)
+		}
+	}
+
+	if len(parseErrors) > 0 {
+		return deleted, parseErrors
+	}
+
+	return deleted, nil
+}
+
+// DeleteRRSet deletes every record in the zone with the given name and type, looking them up first.
+// It returns exactly the records that were deleted, which is none if the set didn't exist. In the
+// case where only some records are deleted returns both the records that were deleted and an
+// error. As with DeleteRecords, system records are kept unless `AllowSystemRecordDeletion` is set.
+func (p *Provider) DeleteRRSet(ctx context.Context, zone string, name string, rtype string) ([]libdns.Record, error) {
+	if rtype == "" {
+		return nil, fmt.Errorf("A record type is required to delete an RRset")
+	}
+
+	return p.DeleteRecords(ctx, zone, []libdns.Record{{Name: name, Type: rtype}})
+}
+
+// DeleteAllRecords deletes every record in the zone, e.g. to reset a zone between tests. It returns
+// the records that were deleted. In the case where only some records are deleted returns both the
+// records that were deleted and an error.
+//
+// Records are removed exactly as NFSN lists them, so records of types this package can't parse are
+// deleted too; they're returned as NFSN reported them (see `PassthroughTypes`). Only member records
+// are deleted unless `AllowSystemRecordDeletion` is set, and read-only records (see
+// `ErrReadOnlyRecord`) are always kept.
+func (p *Provider) DeleteAllRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	nRecords, err := p.listRecords(ctx, zone, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []Operation
+
+	for _, nRecord := range nRecords {
+		if (nRecord.Scope == systemScope && !p.AllowSystemRecordDeletion) || readOnlyTypes[nRecord.Type] {
+			continue
+		}
+
+		record, parseErr := p.toLibdnsRecord(nRecord)
+
+		if parseErr != nil {
+			record = nRecord.RawRecord()
+
+			if p.UnicodeNames {
+				record.Name = convert.UnicodeName(record.Name)
+			}
+		}
+
+		ops = append(ops, p.removeOperation(ctx, nRecord, record, parseErr == nil))
+	}
+
+	return p.applyOperations(ctx, zone, ops)
+}
+
+// Returns the operation that removes `nRecord`, a record as NFSN listed it, reporting it as
+// `record`. Records that were `parsed` are removed as DeleteRecords would, so that e.g. the priority
+// of MX and SRV records, which NFSN lists separately, is sent as part of the data. Others are
+// removed with their data exactly as listed.
+func (p *Provider) removeOperation(ctx context.Context, nRecord nfsnRecord, record libdns.Record, parsed bool) Operation {
+	var params url.Values
+	var err error
+
+	if parsed {
+		params, err = p.toNfsnRecordParameters(withRemoval(ctx), record)
+	}
+
+	if !parsed || err != nil {
+		params = url.Values{}
+		params.Set("name", nRecord.Name)
+		params.Set("type", nRecord.Type)
+		params.Set("data", nRecord.Data)
+	}
+
+	return Operation{Verb: "removeRR", Parameters: params, Records: []libdns.Record{record}}
+}
+
+// Replaces each of `records` that has an empty value with the records in the zone that have the
+// same name and type, ignoring the case of the type. System records are left out unless
+// `AllowSystemRecordDeletion` is set. Records with a value are kept as they are.
+func (p *Provider) resolveDeletions(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	resolved := make([]libdns.Record, 0, len(records))
+
+	for _, record := range records {
+		if record.Value != "" {
+			resolved = append(resolved, record)
+			continue
+		}
+
+		name, err := convert.WildcardName(record.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		recordType := strings.ToUpper(record.Type)
+		matches, err := p.getRecords(ctx, zone, filterName(name), recordType)
+		var parseErrors RecordParseErrors
+
+		if err != nil && !errors.As(err, &parseErrors) {
+			return nil, err
+		}
+
+		// NFSN may match names differently, e.g. ignoring case, so check every match
+		for _, match := range matches {
+			if convert.ASCIIName(match.Name) != convert.ASCIIName(name) || match.Type != recordType {
+				continue
+			}
+
+			// NFSN rejects their removal, so they'd stop the rest of the records being deleted
+			if IsSystemRecord(match) && !p.AllowSystemRecordDeletion {
+				continue
+			}
+
+			resolved = append(resolved, match)
+		}
+	}
+
+	return resolved, nil
+}
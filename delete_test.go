@@ -0,0 +1,311 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+const deleteTestZone = `[
+	{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+	{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+	{"name": "_acme-challenge", "type": "TXT", "data": "token1", "ttl": 180, "scope": "member"},
+	{"name": "_acme-challenge", "type": "TXT", "data": "token2", "ttl": 180, "scope": "member"},
+	{"name": "", "type": "TXT", "data": "v=spf1 -all", "ttl": 3600, "scope": "member"}
+]`
+
+// Returns a provider backed by a server holding `deleteTestZone`, along with a pointer to the list
+// of "type name data" strings for each record it was asked to remove.
+func newDeleteTestProvider(t *testing.T) (*Provider, *[]string) {
+	var removed []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(deleteTestZone))
+		case "/dns/example.com/removeRR":
+			removed = append(removed, r.PostForm.Get("type")+" "+r.PostForm.Get("name")+" "+r.PostForm.Get("data"))
+		}
+	})
+
+	return p, &removed
+}
+
+func TestDeleteRecordsWhere(t *testing.T) {
+	p, removed := newDeleteTestProvider(t)
+
+	deleted, err := p.DeleteRecordsWhere(context.Background(), "example.com.", func(r libdns.Record) bool {
+		return r.Type == "TXT" && strings.HasPrefix(r.Name, "_acme-challenge")
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"TXT _acme-challenge token1", "TXT _acme-challenge token2"}
+
+	if !reflect.DeepEqual(*removed, expected) {
+		t.Errorf("Expected %v but got %v", expected, *removed)
+	}
+
+	if len(deleted) != 2 {
+		t.Errorf("Expected 2 deleted records but got %+v", deleted)
+	}
+}
+
+func TestDeleteRecordsWhereSkipsSystemRecords(t *testing.T) {
+	p, removed := newDeleteTestProvider(t)
+	matchNS := func(r libdns.Record) bool { return r.Type == "NS" }
+
+	deleted, err := p.DeleteRecordsWhere(context.Background(), "example.com.", matchNS)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(deleted) != 0 || len(*removed) != 0 {
+		t.Errorf("Expected system records to be skipped but deleted %+v", deleted)
+	}
+
+	p.AllowSystemRecordDeletion = true
+	deleted, err = p.DeleteRecordsWhere(context.Background(), "example.com.", matchNS)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(deleted) != 1 || len(*removed) != 1 {
+		t.Errorf("Expected the system NS record to be deleted but deleted %+v", deleted)
+	}
+}
+
+func TestDeleteRecordsExactMatch(t *testing.T) {
+	p, removed := newDeleteTestProvider(t)
+	records := []libdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "token2"}}
+
+	deleted, err := p.DeleteRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"TXT _acme-challenge token2"}
+
+	if !reflect.DeepEqual(*removed, expected) {
+		t.Errorf("Expected %v but got %v", expected, *removed)
+	}
+
+	if !reflect.DeepEqual(deleted, records) {
+		t.Errorf("Expected %+v but got %+v", records, deleted)
+	}
+}
+
+func TestDeleteRecordsByNameAndType(t *testing.T) {
+	p, removed := newDeleteTestProvider(t)
+	records := []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge"},
+		{Type: "TXT", Name: ""},
+	}
+
+	deleted, err := p.DeleteRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"TXT _acme-challenge token1", "TXT _acme-challenge token2", "TXT  v=spf1 -all"}
+
+	if !reflect.DeepEqual(*removed, expected) {
+		t.Errorf("Expected %v but got %v", expected, *removed)
+	}
+
+	if len(deleted) != 3 || deleted[0].Value != "token1" || deleted[1].Value != "token2" {
+		t.Errorf("Expected the resolved records but got %+v", deleted)
+	}
+}
+
+func TestDeleteRecordsByNameAndTypeSkipsSystemRecords(t *testing.T) {
+	p, removed := newDeleteTestProvider(t)
+	records := []libdns.Record{
+		{Type: "ns", Name: ""},
+		{Type: "txt", Name: "_acme-challenge"},
+	}
+
+	_, err := p.DeleteRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"TXT _acme-challenge token1", "TXT _acme-challenge token2"}
+
+	if !reflect.DeepEqual(*removed, expected) {
+		t.Errorf("Expected %v but got %v", expected, *removed)
+	}
+
+	*removed = nil
+	p.AllowSystemRecordDeletion = true
+
+	_, err = p.DeleteRecords(context.Background(), "example.com.", records[:1])
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !reflect.DeepEqual(*removed, []string{"NS  ns.phx1.nearlyfreespeech.net."}) {
+		t.Errorf("Expected the system record to be deleted but got %v", *removed)
+	}
+}
+
+func TestDeleteRRSet(t *testing.T) {
+	p, removed := newDeleteTestProvider(t)
+	deleted, err := p.DeleteRRSet(context.Background(), "example.com.", "_acme-challenge.example.com.", "TXT")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"TXT _acme-challenge token1", "TXT _acme-challenge token2"}
+
+	if !reflect.DeepEqual(*removed, expected) {
+		t.Errorf("Expected %v but got %v", expected, *removed)
+	}
+
+	if len(deleted) != 2 || deleted[0].Value != "token1" || deleted[1].Value != "token2" {
+		t.Errorf("Expected the deleted records but got %+v", deleted)
+	}
+
+	*removed = nil
+	deleted, err = p.DeleteRRSet(context.Background(), "example.com.", "missing", "TXT")
+
+	if err != nil || len(deleted) != 0 || len(*removed) != 0 {
+		t.Errorf("Expected nothing to be deleted but got %+v, %v", deleted, err)
+	}
+
+	if _, err = p.DeleteRRSet(context.Background(), "example.com.", "www", ""); err == nil {
+		t.Errorf("Expected an error without a type")
+	}
+}
+
+func TestDeleteAllRecords(t *testing.T) {
+	var removed []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[
+				{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+				{"name": "", "type": "DS", "data": "60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118", "ttl": 3600, "scope": "member"},
+				{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+				{"name": "", "type": "MX", "data": "mail.example.com.", "ttl": 3600, "scope": "member", "aux": 10},
+				{"name": "_xmpp._tcp", "type": "SRV", "data": "5 5222 xmpp.example.com.", "ttl": 3600, "scope": "member", "aux": 20},
+				{"name": "_sip._tcp", "type": "SRV", "data": "heavy 5060 sip.example.com.", "ttl": 3600, "scope": "member", "aux": 10}
+			]`))
+		case "/dns/example.com/removeRR":
+			removed = append(removed, r.PostForm.Get("type")+" "+r.PostForm.Get("name")+" "+r.PostForm.Get("data"))
+		}
+	})
+
+	deleted, err := p.DeleteAllRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Priorities are sent with the data, but the unparseable SRV record is deleted as it was listed
+	expected := []string{
+		"A www 192.0.2.1",
+		"MX  10 mail.example.com.",
+		"SRV _xmpp._tcp 20 5 5222 xmpp.example.com.",
+		"SRV _sip._tcp heavy 5060 sip.example.com.",
+	}
+
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected %v but got %v", expected, removed)
+	}
+
+	if len(deleted) != 4 || deleted[3].Value != "heavy 5060 sip.example.com." {
+		t.Errorf("Expected the deleted records but got %+v", deleted)
+	}
+}
+
+func TestDeleteRecordsContinueOnError(t *testing.T) {
+	var removed []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		removed = append(removed, r.PostForm.Get("data"))
+
+		if r.PostForm.Get("data") == "token1" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	p.ContinueOnError = true
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "token1"},
+		{Type: "TXT", Name: "_acme-challenge", Value: "token2"},
+		{Type: "A", Name: "www", Value: "192.0.2.1"},
+	}
+
+	deleted, err := p.DeleteRecords(context.Background(), "example.com.", records)
+	var batchErr *BatchError
+
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected BatchError but got %v", err)
+	}
+
+	expected := []string{"token1", "token2", "192.0.2.1"}
+
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected %v but got %v", expected, removed)
+	}
+
+	if !reflect.DeepEqual(deleted, records[1:]) {
+		t.Errorf("Expected %+v but got %+v", records[1:], deleted)
+	}
+
+	if !reflect.DeepEqual(batchErr.Failed(), records[:1]) {
+		t.Errorf("Expected %+v to be failed but got %+v", records[:1], batchErr.Failed())
+	}
+}
+
+func TestDeleteRecordsWhereEmptyValue(t *testing.T) {
+	var removed []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[
+				{"name": "flag", "type": "TXT", "data": "\"\"", "ttl": 3600, "scope": "member"},
+				{"name": "flag", "type": "TXT", "data": "keep-me", "ttl": 3600, "scope": "member"}
+			]`))
+		case "/dns/example.com/removeRR":
+			removed = append(removed, r.PostForm.Get("data"))
+		}
+	})
+
+	// A match with an empty value is removed alone, not as a wildcard for its set
+	deleted, err := p.DeleteRecordsWhere(context.Background(), "example.com.", func(r libdns.Record) bool {
+		return r.Value == ""
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(removed) != 1 || removed[0] == "keep-me" || len(deleted) != 1 {
+		t.Errorf("Expected only the empty record to be removed but removed %v", removed)
+	}
+}
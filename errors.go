@@ -0,0 +1,270 @@
+package nfsn
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// RecordParseError describes a record returned by NFSN that could not be converted to a
+// libdns.Record. The raw fields from the API response are preserved for inspection.
+type RecordParseError struct {
+	Name string
+	Type string
+	Data string
+	TTL  int
+	Aux  int
+
+	// The reason the record could not be converted
+	Err error
+}
+
+func (e RecordParseError) Error() string {
+	return fmt.Sprintf("Failed to parse %s record %q with data %q: %v", e.Type, e.Name, e.Data, e.Err)
+}
+
+func (e RecordParseError) Unwrap() error {
+	return e.Err
+}
+
+// RecordParseErrors is returned by `GetRecords`, alongside the records that were converted
+// successfully, when one or more records in the zone could not be converted.
+type RecordParseErrors []RecordParseError
+
+func (e RecordParseErrors) Error() string {
+	messages := make([]string, 0, len(e))
+
+	for _, parseError := range e {
+		messages = append(messages, parseError.Error())
+	}
+
+	return fmt.Sprintf("%d record(s) could not be parsed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// ConflictError is returned by AppendRecords, when `CheckConflicts` is enabled, if a record can't
+// be added because a CNAME would share its name with another record.
+type ConflictError struct {
+	// The record that was to be added
+	Record libdns.Record
+
+	// The record it conflicts with, either already in the zone or added earlier in the same call
+	Existing libdns.Record
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Can't add %s record %q: it conflicts with the existing %s record %q with value %q; a CNAME can't share its name with any other record",
+		e.Record.Type, e.Record.Name, e.Existing.Type, e.Existing.Name, e.Existing.Value)
+}
+
+// RollbackError is returned by SetRecords, when `RollbackOnError` is enabled, if some of the records
+// could not be written. Err is the original failure. If RollbackErr is nil the zone was restored to
+// its state before the call; otherwise the zone may be partially updated.
+type RollbackError struct {
+	Err         error
+	RollbackErr error
+}
+
+func (e *RollbackError) Error() string {
+	if e.RollbackErr == nil {
+		return fmt.Sprintf("Failed to set records, changes were rolled back: %v", e.Err)
+	}
+
+	return fmt.Sprintf("Failed to set records: %v. Rolling back changes also failed, the zone may be partially updated: %v", e.Err, e.RollbackErr)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// RecordResult is the outcome of writing or deleting a single record in a batch.
+type RecordResult struct {
+	Record libdns.Record
+
+	// True if the call covering the record was made, i.e. the batch hadn't already been stopped by
+	// an earlier failure
+	Attempted bool
+
+	// The error the call failed with, if it failed
+	Err error
+}
+
+// Succeeded returns true if the record was written or deleted.
+func (r RecordResult) Succeeded() bool {
+	return r.Attempted && r.Err == nil
+}
+
+// BatchError is returned by AppendRecords, SetRecords, DeleteRecords, and SyncZone when some of the
+// records in the call couldn't be processed, alongside the records that were. It has a result for
+// each record the call processed, in the order they were passed in, so that only the records that
+// failed can be retried. Records the call looked up itself, e.g. those DeleteRecords matches for a
+// record with no value, are reported as looked up.
+type BatchError struct {
+	Results []RecordResult
+}
+
+func (e *BatchError) Error() string {
+	var messages []string
+	notAttempted := 0
+
+	for _, result := range e.Results {
+		if !result.Attempted {
+			notAttempted++
+		} else if result.Err != nil {
+			messages = append(messages, fmt.Sprintf("%s record %q: %v", result.Record.Type, result.Record.Name, result.Err))
+		}
+	}
+
+	message := fmt.Sprintf("%d of %d record(s) failed: %s", len(messages), len(e.Results), strings.Join(messages, "; "))
+
+	if notAttempted > 0 {
+		message += fmt.Sprintf(". %d record(s) were not attempted", notAttempted)
+	}
+
+	return message
+}
+
+// Unwrap returns the error of the first record that failed.
+func (e *BatchError) Unwrap() error {
+	for _, result := range e.Results {
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+
+	return nil
+}
+
+// Failed returns the records that weren't processed, whether they failed or weren't attempted.
+func (e *BatchError) Failed() []libdns.Record {
+	var failed []libdns.Record
+
+	for _, result := range e.Results {
+		if !result.Succeeded() {
+			failed = append(failed, result.Record)
+		}
+	}
+
+	return failed
+}
+
+// Returns `err` with its results, if it's a *BatchError, in the order of `records`, which are the
+// records the batch was made from. NFSN is only sent each record once, so repeats of a record share
+// its result.
+func orderBatchError(err error, records []libdns.Record) error {
+	batchErr, ok := err.(*BatchError)
+
+	if !ok {
+		return err
+	}
+
+	resultFor := make(map[libdns.Record]RecordResult)
+
+	for _, result := range batchErr.Results {
+		resultFor[result.Record] = result
+	}
+
+	results := make([]RecordResult, 0, len(records))
+	seen := make(map[libdns.Record]bool)
+
+	for _, record := range records {
+		result, ok := resultFor[record]
+
+		if !ok {
+			return err
+		}
+
+		results = append(results, result)
+		seen[record] = true
+	}
+
+	// The calls covered records other than `records`, so there's no order to restore
+	if len(seen) != len(resultFor) {
+		return err
+	}
+
+	return &BatchError{Results: results}
+}
+
+// APIError is returned, possibly wrapped, when NFSN responds to a request with a non-success status.
+// NFSN describes failures with a JSON body of the form {"error": "...", "debug": "..."}. The
+// description is in Message rather than a field named Error, which would clash with the method.
+type APIError struct {
+	StatusCode int
+
+	// The `error` field of the response, a short description of the failure
+	Message string
+
+	// The `debug` field of the response, which often explains the failure in more detail
+	Debug string
+
+	// The response body, if it wasn't a JSON error
+	Body string
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("API returned status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+
+	if e.Debug != "" {
+		message += " (" + e.Debug + ")"
+	}
+
+	if e.Message == "" && e.Debug == "" && e.Body != "" {
+		message += " with response body " + e.Body
+	}
+
+	return message
+}
+
+// Returns an APIError for a response with status `statusCode` and body `body`.
+func parseAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+	var fields struct {
+		Error string `json:"error"`
+		Debug string `json:"debug"`
+	}
+
+	if json.Unmarshal(body, &fields) == nil && (fields.Error != "" || fields.Debug != "") {
+		apiErr.Message = fields.Error
+		apiErr.Debug = fields.Debug
+	} else {
+		apiErr.Body = strings.TrimSpace(string(body))
+	}
+
+	return apiErr
+}
+
+// Returns an APIError for the failed response `resp` with body `body`, with any secrets NFSN echoed
+// back masked (see `redactSecrets`).
+func (p *Provider) apiError(resp *http.Response, body []byte) *APIError {
+	apiErr := parseAPIError(resp.StatusCode, body)
+	apiErr.Message = p.redactSecrets(apiErr.Message, sentSecrets(resp)...)
+	apiErr.Debug = p.redactSecrets(apiErr.Debug, sentSecrets(resp)...)
+	apiErr.Body = p.redactSecrets(apiErr.Body, sentSecrets(resp)...)
+	return apiErr
+}
+
+// An error that matches `sentinel` with errors.Is while unwrapping to `err`, so that both the
+// sentinel and e.g. an *APIError it was caused by can be found.
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func (e *sentinelError) Error() string {
+	return fmt.Sprintf("%v: %v", e.sentinel, e.err)
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.err
+}
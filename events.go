@@ -0,0 +1,82 @@
+package nfsn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	// EventTTLWarning is emitted when a record is written with a TTL outside the recommended bounds
+	// for its type. The write still goes ahead.
+	EventTTLWarning EventType = "TTLWarning"
+)
+
+// Event is passed to `Provider.OnEvent` to report noteworthy, non-fatal occurrences.
+type Event struct {
+	Type    EventType
+	Record  libdns.Record
+	Message string
+}
+
+// Recommended TTL bounds for a record type. A zero bound is unchecked.
+type ttlBounds struct {
+	min time.Duration
+	max time.Duration
+}
+
+// Record types that benefit from TTLs within particular bounds. Delegation and mail routing records
+// change rarely and are expensive to look up when they expire from caches.
+var recommendedTTLs = map[string]ttlBounds{
+	"NS": {min: time.Hour, max: 48 * time.Hour},
+	"MX": {min: 5 * time.Minute, max: 48 * time.Hour},
+}
+
+// Sends `event` to the OnEvent callback, if there is one, and logs it.
+func (p *Provider) emit(event Event) {
+	p.logf("%s: %s", event.Type, event.Message)
+
+	if p.OnEvent != nil {
+		p.OnEvent(event)
+	}
+}
+
+// Emits an EventTTLWarning if `CheckTTLs` is enabled and the TTL `record` will be written with is
+// outside the recommended bounds for its type.
+func (p *Provider) checkTTL(ctx context.Context, record libdns.Record) {
+	if !p.CheckTTLs {
+		return
+	}
+
+	bounds, ok := recommendedTTLs[record.Type]
+
+	if !ok {
+		return
+	}
+
+	ttl := p.ttlForNfsn(ctx, record.TTL)
+
+	// NFSN's default is outside our control
+	if ttl == 0 {
+		return
+	}
+
+	if bounds.min > 0 && ttl < bounds.min {
+		p.emit(Event{
+			Type:    EventTTLWarning,
+			Record:  record,
+			Message: fmt.Sprintf("%s record %q has TTL %s, below the recommended minimum of %s", record.Type, record.Name, ttl, bounds.min),
+		})
+	} else if bounds.max > 0 && ttl > bounds.max {
+		p.emit(Event{
+			Type:    EventTTLWarning,
+			Record:  record,
+			Message: fmt.Sprintf("%s record %q has TTL %s, above the recommended maximum of %s", record.Type, record.Name, ttl, bounds.max),
+		})
+	}
+}
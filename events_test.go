@@ -0,0 +1,44 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestTTLWarning(t *testing.T) {
+	var events []Event
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {})
+	p.CheckTTLs = true
+	p.OnEvent = func(event Event) {
+		events = append(events, event)
+	}
+
+	records := []libdns.Record{
+		{Type: "NS", Name: "sub", Value: "ns.example.net.", TTL: 180 * time.Second},
+		{Type: "NS", Name: "sub", Value: "ns2.example.net.", TTL: 24 * time.Hour},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 180 * time.Second},
+	}
+
+	added, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(added) != len(records) {
+		t.Errorf("Expected warnings not to block writes, but only %d records were added", len(added))
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event but got %d", len(events))
+	}
+
+	if events[0].Type != EventTTLWarning || events[0].Record.Value != "ns.example.net." {
+		t.Errorf("Unexpected event %+v", events[0])
+	}
+}
@@ -0,0 +1,170 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Address is an A or AAAA record.
+type Address struct {
+	Name string
+	TTL  time.Duration
+	IP   netip.Addr
+}
+
+// TXT is a TXT record. Text is the record's strings joined together.
+type TXT struct {
+	Name string
+	TTL  time.Duration
+	Text string
+}
+
+// MX is an MX record.
+type MX struct {
+	Name       string
+	TTL        time.Duration
+	Preference uint16
+	Target     string
+}
+
+// GetAddresses lists the A and AAAA records in the zone with the given name, or every name if
+// `name` is empty. As with GetRecords, records that can't be converted are skipped and reported in
+// a `RecordParseErrors` returned alongside the rest.
+func (p *Provider) GetAddresses(ctx context.Context, zone string, name string) ([]Address, error) {
+	records, err := p.getTypedRecords(ctx, zone, name, "")
+
+	if records == nil {
+		return nil, err
+	}
+
+	var addresses []Address
+
+	for _, record := range records {
+		if record.Type != "A" && record.Type != "AAAA" {
+			continue
+		}
+
+		ip, parseErr := netip.ParseAddr(record.Value)
+
+		if parseErr != nil {
+			err = appendParseError(err, record, parseErr)
+			continue
+		}
+
+		addresses = append(addresses, Address{Name: record.Name, TTL: record.TTL, IP: ip})
+	}
+
+	return addresses, err
+}
+
+// GetTXTRecords lists the TXT records in the zone with the given name, or every name if `name` is
+// empty. Unparseable records are handled as in GetAddresses.
+func (p *Provider) GetTXTRecords(ctx context.Context, zone string, name string) ([]TXT, error) {
+	records, err := p.getTypedRecords(ctx, zone, name, "TXT")
+
+	if records == nil {
+		return nil, err
+	}
+
+	var txts []TXT
+
+	for _, record := range records {
+		if record.Type != "TXT" {
+			continue
+		}
+
+		txts = append(txts, TXT{Name: record.Name, TTL: record.TTL, Text: record.Value})
+	}
+
+	return txts, err
+}
+
+// GetMXRecords lists the MX records in the zone with the given name, or every name if `name` is
+// empty. Unparseable records are handled as in GetAddresses.
+func (p *Provider) GetMXRecords(ctx context.Context, zone string, name string) ([]MX, error) {
+	records, err := p.getTypedRecords(ctx, zone, name, "MX")
+
+	if records == nil {
+		return nil, err
+	}
+
+	var mxs []MX
+
+	for _, record := range records {
+		if record.Type != "MX" {
+			continue
+		}
+
+		if record.Priority > 65535 {
+			err = appendParseError(err, record, fmt.Errorf("Preference %d is out of range", record.Priority))
+			continue
+		}
+
+		mxs = append(mxs, MX{Name: record.Name, TTL: record.TTL, Preference: uint16(record.Priority), Target: record.Value})
+	}
+
+	return mxs, err
+}
+
+// Lists the records in the zone with the given name and type, keeping any records that could be
+// converted along with a RecordParseErrors. Returns nil records for any other error.
+func (p *Provider) getTypedRecords(ctx context.Context, zone string, name string, recordType string) ([]libdns.Record, error) {
+	records, err := p.GetRecordsFiltered(ctx, zone, RecordFilter{Name: name, Type: recordType})
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return nil, err
+	}
+
+	return records, err
+}
+
+// Adds a RecordParseError for `record` to the RecordParseErrors `err`, which may be nil.
+func appendParseError(err error, record libdns.Record, parseErr error) error {
+	var parseErrors RecordParseErrors
+	errors.As(err, &parseErrors)
+
+	return append(parseErrors, RecordParseError{
+		Name: record.Name,
+		Type: record.Type,
+		Data: record.Value,
+		TTL:  int(record.TTL.Seconds()),
+		Err:  parseErr,
+	})
+}
+
+// RecordExists returns true if the zone has a record with the name, type, and value (and priority
+// where it has one) of `record`, compared as they would be stored (see `Equal`). Its TTL isn't
+// compared. Only records with the same name and type are fetched, so this is cheap even for large
+// zones.
+func (p *Provider) RecordExists(ctx context.Context, zone string, record libdns.Record) (bool, error) {
+	name, err := relativeName(record.Name, zone)
+
+	if err != nil {
+		return false, err
+	}
+
+	record.Name = name
+	record = Normalize(record)
+	existing, err := p.getRecords(ctx, zone, filterName(record.Name), record.Type)
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return false, err
+	}
+
+	for _, candidate := range existing {
+		candidate.TTL = record.TTL
+
+		if Equal(candidate, record) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
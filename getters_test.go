@@ -0,0 +1,114 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestTypedGetters(t *testing.T) {
+	var body string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		body = r.PostForm.Encode()
+		w.Write([]byte(`[
+			{"name": "", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+			{"name": "", "type": "AAAA", "data": "2001:db8::1", "ttl": 3600, "scope": "member"},
+			{"name": "", "type": "A", "data": "not-an-address", "ttl": 3600, "scope": "member"},
+			{"name": "", "type": "MX", "data": "mail.example.com.", "ttl": 600, "scope": "member", "aux": 10},
+			{"name": "", "type": "TXT", "data": "\"v=spf1 \" \"-all\"", "ttl": 600, "scope": "member"}
+		]`))
+	})
+
+	addresses, err := p.GetAddresses(context.Background(), "example.com.", "@")
+	var parseErrors RecordParseErrors
+
+	if !errors.As(err, &parseErrors) || len(parseErrors) != 1 || parseErrors[0].Data != "not-an-address" {
+		t.Errorf("Expected a parse error for the invalid address but got %v", err)
+	}
+
+	expectedAddresses := []Address{
+		{Name: "", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.1")},
+		{Name: "", TTL: time.Hour, IP: netip.MustParseAddr("2001:db8::1")},
+	}
+
+	if !reflect.DeepEqual(addresses, expectedAddresses) {
+		t.Errorf("Expected %+v but got %+v", expectedAddresses, addresses)
+	}
+
+	// The apex is filtered locally
+	if body != "" {
+		t.Errorf("Expected an empty body but got '%s'", body)
+	}
+
+	mxs, err := p.GetMXRecords(context.Background(), "example.com.", "")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if body != "type=MX" {
+		t.Errorf("Expected 'type=MX' but got '%s'", body)
+	}
+
+	if expected := []MX{{Name: "", TTL: 10 * time.Minute, Preference: 10, Target: "mail.example.com."}}; !reflect.DeepEqual(mxs, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, mxs)
+	}
+
+	txts, err := p.GetTXTRecords(context.Background(), "example.com.", "")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if expected := []TXT{{Name: "", TTL: 10 * time.Minute, Text: "v=spf1 -all"}}; !reflect.DeepEqual(txts, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, txts)
+	}
+}
+
+func TestRecordExists(t *testing.T) {
+	var query string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		query = r.PostForm.Encode()
+		w.Write([]byte(`[
+			{"name": "", "type": "MX", "data": "mail1.example.com.", "ttl": 3600, "scope": "member", "aux": 10},
+			{"name": "", "type": "MX", "data": "mail2.example.com.", "ttl": 3600, "scope": "member", "aux": 20}
+		]`))
+	})
+
+	cases := []struct {
+		record libdns.Record
+		exists bool
+	}{
+		{libdns.Record{Type: "MX", Name: "@", Value: "Mail2.example.com", Priority: 20}, true},
+		{libdns.Record{Type: "mx", Name: "example.com.", Value: "mail1.example.com.", Priority: 10, TTL: time.Minute}, true},
+		{libdns.Record{Type: "MX", Name: "", Value: "mail1.example.com.", Priority: 20}, false},
+		{libdns.Record{Type: "MX", Name: "", Value: "mail3.example.com."}, false},
+	}
+
+	for _, c := range cases {
+		exists, err := p.RecordExists(context.Background(), "example.com.", c.record)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if exists != c.exists {
+			t.Errorf("%+v: Expected %t but got %t", c.record, c.exists, exists)
+		}
+
+		// The apex can't be filtered by NFSN
+		if query != "type=MX" {
+			t.Errorf("Expected the type to be filtered by NFSN but got '%s'", query)
+		}
+	}
+}
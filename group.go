@@ -0,0 +1,50 @@
+package nfsn
+
+import (
+	"net/url"
+
+	"github.com/libdns/libdns"
+)
+
+// The records in a call that share a name and type, along with the parameters each is sent with.
+// NFSN stores records individually, but MX and TXT sets with several values are only meaningful as
+// a whole, so they're written one set at a time.
+type rrSet struct {
+	key     rrSetKey
+	records []libdns.Record
+	params  []url.Values
+}
+
+// Collapses `records` into record sets, in the order each set first appears in `records`. Names and
+// types are compared as NFSN stores them (see `rrSetKeyFor`). Repeats of a record are dropped, since
+// NFSN would reject, or duplicate, a second write of the same record. `allParams` holds the
+// parameters for each of `records`, and may be nil if they aren't needed.
+func groupRecords(records []libdns.Record, allParams []url.Values) []rrSet {
+	var sets []rrSet
+	index := make(map[rrSetKey]int)
+	seen := make(map[libdns.Record]bool)
+
+	for i, record := range records {
+		if seen[Normalize(record)] {
+			continue
+		}
+
+		seen[Normalize(record)] = true
+		key := rrSetKeyFor(record)
+		j, ok := index[key]
+
+		if !ok {
+			j = len(sets)
+			index[key] = j
+			sets = append(sets, rrSet{key: key})
+		}
+
+		sets[j].records = append(sets[j].records, record)
+
+		if allParams != nil {
+			sets[j].params = append(sets[j].params, allParams[i])
+		}
+	}
+
+	return sets
+}
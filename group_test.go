@@ -0,0 +1,90 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestAppendRecordsGroupsSets(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("type")+" "+r.PostForm.Get("data"))
+	})
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "", Value: "v=spf1 -all"},
+		{Type: "MX", Name: "", Value: "mail1.example.com.", Priority: 10},
+		{Type: "TXT", Name: "", Value: "google-site-verification=abc"},
+		{Type: "MX", Name: "", Value: "mail2.example.com.", Priority: 20},
+		{Type: "TXT", Name: "", Value: "v=spf1 -all"},
+	}
+
+	appended, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"addRR TXT v=spf1 -all",
+		"addRR TXT google-site-verification=abc",
+		"addRR MX 10 mail1.example.com.",
+		"addRR MX 20 mail2.example.com.",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+
+	if len(appended) != 4 {
+		t.Errorf("Expected 4 records but got %d", len(appended))
+	}
+}
+
+func TestSetRecordsGroupsEquivalentNames(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		if r.URL.Path == "/dns/example.com/replaceRRSet" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("data"))
+	})
+
+	records := []libdns.Record{
+		{Type: "A", Name: "@", Value: "192.0.2.1"},
+		{Type: "A", Name: "", Value: "192.0.2.2"},
+		{Type: "a", Name: "example.com.", Value: "192.0.2.3"},
+		{Type: "A", Name: "WWW", Value: "192.0.2.4"},
+		{Type: "A", Name: "www", Value: "192.0.2.5"},
+	}
+
+	_, err := p.SetRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// One replaceRR per set, so that later records don't replace earlier ones
+	expected := []string{
+		"replaceRR 192.0.2.1",
+		"addRR 192.0.2.2",
+		"addRR 192.0.2.3",
+		"replaceRR 192.0.2.4",
+		"addRR 192.0.2.5",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+}
@@ -0,0 +1,83 @@
+package nfsn
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// A `listRRs` request that concurrent callers can wait on
+type inflightList struct {
+	done     chan struct{}
+	nRecords []nfsnRecord
+	err      error
+
+	// The write generation when the request was made, see `noteWrite`
+	generation uint64
+
+	// Whether the request failed because the caller that made it was cancelled
+	cancelled bool
+}
+
+// Fetches the records in the zone matching `filter` (see `fetchRecords`), sharing the request and
+// its result with any concurrent calls for the same zone and filter rather than each making their
+// own. A request is only shared if no write has been made since it started, so that reading after
+// a write always sees it. The shared request runs with the context of the caller that started it;
+// if that caller is cancelled, waiting callers that haven't been make the request again
+// themselves. Callers must not modify the returned slice.
+func (p *Provider) listRecords(ctx context.Context, zone string, filter url.Values) ([]nfsnRecord, error) {
+	key := strings.TrimRight(zone, ".") + "?" + filter.Encode()
+
+	for {
+		p.inflightMtx.Lock()
+		call, ok := p.inflight[key]
+
+		if !ok || call.generation != p.writeGeneration {
+			break
+		}
+
+		p.inflightMtx.Unlock()
+
+		select {
+		case <-call.done:
+			if !call.cancelled {
+				return call.nRecords, call.err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call := &inflightList{done: make(chan struct{}), generation: p.writeGeneration}
+
+	if p.inflight == nil {
+		p.inflight = make(map[string]*inflightList)
+	}
+
+	p.inflight[key] = call
+	p.inflightMtx.Unlock()
+
+	call.nRecords, call.err = p.fetchRecords(ctx, zone, filter)
+	call.cancelled = call.err != nil && ctx.Err() != nil
+
+	p.inflightMtx.Lock()
+
+	// A later request may have taken its place
+	if p.inflight[key] == call {
+		delete(p.inflight, key)
+	}
+
+	p.inflightMtx.Unlock()
+	close(call.done)
+
+	return call.nRecords, call.err
+}
+
+// Records that a write is starting or has finished. It's called at both, so that a read made while
+// the write was in progress isn't shared with reads made after it.
+func (p *Provider) noteWrite() {
+	p.inflightMtx.Lock()
+	defer p.inflightMtx.Unlock()
+
+	p.writeGeneration++
+}
@@ -0,0 +1,152 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestConcurrentGetRecordsShareRequest(t *testing.T) {
+	var requests int32
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600}]`))
+	})
+
+	const callers = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			<-start
+
+			records, err := p.GetRecords(context.Background(), "example.com.")
+
+			if err == nil && len(records) != 1 {
+				t.Errorf("Expected 1 record but got %d", len(records))
+			}
+
+			errs <- err
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request but got %d", requests)
+	}
+}
+
+func TestSharedRequestRetriedAfterLeaderCancelled(t *testing.T) {
+	var requests int32
+	started := make(chan struct{})
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+
+		w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600}]`))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+
+	go func() {
+		_, err := p.GetRecords(ctx, "example.com.")
+		leaderErr <- err
+	}()
+
+	<-started
+	waiterErr := make(chan error, 1)
+	var records []libdns.Record
+
+	go func() {
+		var err error
+		records, err = p.GetRecords(context.Background(), "example.com.")
+		waiterErr <- err
+	}()
+
+	// Give the waiter time to join the shared request before it fails
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; err == nil {
+		t.Errorf("Expected the cancelled caller to fail")
+	}
+
+	if err := <-waiterErr; err != nil || len(records) != 1 {
+		t.Errorf("Expected the waiting caller to get 1 record but got %d and %v", len(records), err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests but got %d", requests)
+	}
+}
+
+func TestReadAfterWriteNotShared(t *testing.T) {
+	var lists int32
+	started := make(chan struct{})
+	var stored []string
+	var mtx sync.Mutex
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/addRR":
+			mtx.Lock()
+			stored = append(stored, `{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 180}`)
+			mtx.Unlock()
+		case "/dns/example.com/listRRs":
+			// The first read is slow, and from before the write
+			if atomic.AddInt32(&lists, 1) == 1 {
+				close(started)
+				time.Sleep(200 * time.Millisecond)
+				w.Write([]byte(`[]`))
+				return
+			}
+
+			mtx.Lock()
+			w.Write([]byte("[" + strings.Join(stored, ",") + "]"))
+			mtx.Unlock()
+		}
+	})
+	p.VerifyAfterWrite = true
+
+	go p.GetRecords(context.Background(), "example.com.")
+	<-started
+
+	added, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}})
+
+	if err != nil || len(added) != 1 {
+		t.Errorf("Expected the verified record but got %+v and %v", added, err)
+	}
+
+	if lists != 2 {
+		t.Errorf("Expected 2 listRRs requests but got %d", lists)
+	}
+}
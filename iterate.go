@@ -0,0 +1,165 @@
+//go:build go1.23
+
+package nfsn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// IterateRecords yields the records in the zone one at a time, as GetRecords would return them,
+// decoding each `listRRs` response as it goes rather than building a slice of the whole zone. This
+// keeps memory use down for zones with tens of thousands of records; the response body itself is
+// still read in full, since it may need to be retried.
+//
+// Records that can't be converted are yielded as a RecordParseError and iteration continues. Any
+// other error ends the iteration after it is yielded. Breaking out of the loop stops any further
+// pages from being requested.
+func (p *Provider) IterateRecords(ctx context.Context, zone string) iter.Seq2[libdns.Record, error] {
+	return func(yield func(libdns.Record, error) bool) {
+		var body io.Reader
+		seen := make(map[string]bool)
+
+		for {
+			resp, err := p.makeRequest(ctx, "POST", p.uriForZone(zone, "listRRs"), body)
+
+			if err != nil {
+				yield(libdns.Record{}, err)
+				return
+			}
+
+			next, more, err := p.yieldRecordPage(resp.Body, zone, yield)
+
+			if err != nil {
+				yield(libdns.Record{}, fmt.Errorf("Failed to decode records for zone %s: %w", zone, err))
+				return
+			}
+
+			if !more || next == "" {
+				return
+			}
+
+			err = checkNextPage(seen, next)
+
+			if err != nil {
+				yield(libdns.Record{}, err)
+				return
+			}
+
+			body = strings.NewReader(url.Values{"next": {next}}.Encode())
+		}
+	}
+}
+
+// Decodes a `listRRs` response from `r`, in either of the forms `parseRecordPage` accepts, yielding
+// each record as it's decoded. Returns the token for the next page, if any, and false if `yield`
+// asked to stop.
+func (p *Provider) yieldRecordPage(r io.Reader, zone string, yield func(libdns.Record, error) bool) (string, bool, error) {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+
+	if errors.Is(err, io.EOF) {
+		return "", true, nil
+	}
+
+	if err != nil {
+		return "", true, err
+	}
+
+	switch token {
+	case nil:
+		return "", true, nil
+	case json.Delim('['):
+		more, err := p.yieldRecordArray(decoder, zone, yield)
+		return "", more, err
+	case json.Delim('{'):
+	default:
+		return "", true, fmt.Errorf("Unexpected %v at the start of the response", token)
+	}
+
+	var next string
+
+	for decoder.More() {
+		key, err := decoder.Token()
+
+		if err != nil {
+			return "", true, err
+		}
+
+		switch key {
+		case "records":
+			token, err := decoder.Token()
+
+			if err != nil {
+				return "", true, err
+			}
+
+			if token == nil {
+				continue
+			}
+
+			if token != json.Delim('[') {
+				return "", true, fmt.Errorf("Expected an array of records but got %v", token)
+			}
+
+			more, err := p.yieldRecordArray(decoder, zone, yield)
+
+			if !more || err != nil {
+				return "", more, err
+			}
+		case "next":
+			err = decoder.Decode(&next)
+		default:
+			var ignored json.RawMessage
+			err = decoder.Decode(&ignored)
+		}
+
+		if err != nil {
+			return "", true, err
+		}
+	}
+
+	return next, true, nil
+}
+
+// Decodes the records in an array whose opening bracket has been read, yielding each one. Returns
+// false if `yield` asked to stop.
+func (p *Provider) yieldRecordArray(decoder *json.Decoder, zone string, yield func(libdns.Record, error) bool) (bool, error) {
+	for decoder.More() {
+		var nRecord nfsnRecord
+		err := decoder.Decode(&nRecord)
+
+		if err != nil {
+			return true, err
+		}
+
+		record, parseErr := p.toLibdnsRecord(nRecord)
+		var more bool
+
+		if parseErr != nil {
+			more = yield(libdns.Record{}, *parseErr)
+		} else {
+			if p.AbsoluteNames {
+				record.Name = libdns.AbsoluteName(record.Name, zone)
+			}
+
+			more = yield(record, nil)
+		}
+
+		if !more {
+			return false, nil
+		}
+	}
+
+	// The closing bracket
+	_, err := decoder.Token()
+	return true, err
+}
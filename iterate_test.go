@@ -0,0 +1,115 @@
+//go:build go1.23
+
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestIterateRecords(t *testing.T) {
+	var nexts []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		nexts = append(nexts, r.PostForm.Get("next"))
+
+		switch r.PostForm.Get("next") {
+		case "":
+			w.Write([]byte(`{"records": [
+				{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+				{"name": "_sip._tcp", "type": "SRV", "data": "heavy 5060 sip.example.com.", "ttl": 3600, "scope": "member", "aux": 10}
+			], "next": "page2"}`))
+		case "page2":
+			w.Write([]byte(`[{"name": "", "type": "TXT", "data": "v=spf1 -all", "ttl": 3600, "scope": "member"}]`))
+		}
+	})
+
+	var values []string
+	var parseErrors []RecordParseError
+
+	for record, err := range p.IterateRecords(context.Background(), "example.com.") {
+		var parseErr RecordParseError
+
+		if errors.As(err, &parseErr) {
+			parseErrors = append(parseErrors, parseErr)
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		values = append(values, record.Value)
+	}
+
+	expected := []string{"192.0.2.1", "v=spf1 -all"}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v but got %v", expected, values)
+	}
+
+	if len(parseErrors) != 1 || parseErrors[0].Name != "_sip._tcp" {
+		t.Errorf("Expected a parse error for '_sip._tcp' but got %+v", parseErrors)
+	}
+
+	if !reflect.DeepEqual(nexts, []string{"", "page2"}) {
+		t.Errorf("Expected both pages to be requested but got %v", nexts)
+	}
+
+	// Stopping early doesn't request the next page
+	nexts = nil
+
+	for range p.IterateRecords(context.Background(), "example.com.") {
+		break
+	}
+
+	if len(nexts) != 1 {
+		t.Errorf("Expected 1 request but got %d", len(nexts))
+	}
+}
+
+func TestIterateRecordsError(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600}, {"name": `))
+	})
+
+	var errs []error
+	records := 0
+
+	for _, err := range p.IterateRecords(context.Background(), "example.com.") {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			records++
+		}
+	}
+
+	if records != 1 || len(errs) != 1 {
+		t.Errorf("Expected 1 record and then an error but got %d records and %v", records, errs)
+	}
+}
+
+func TestIterateRecordsRepeatedToken(t *testing.T) {
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"records": [{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600}], "next": "again"}`))
+	})
+
+	var errs []error
+
+	for _, err := range p.IterateRecords(context.Background(), "example.com.") {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if requests != 2 || len(errs) != 1 {
+		t.Errorf("Expected an error after 2 requests but got %v after %d", errs, requests)
+	}
+}
@@ -0,0 +1,98 @@
+package nfsn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrKeyringUnsupported is returned when `KeyringService` is set on a platform without a supported
+// keyring.
+var ErrKeyringUnsupported = errors.New("OS keyring is not supported on this platform")
+
+// Looks up the secret stored in the OS keyring for `service` and `account`. A variable so tests
+// can replace it.
+var keyringLookup = lookupKeyring
+
+// Reads a secret with the platform's keyring tool: `security` for the macOS Keychain and
+// `secret-tool` for the Secret Service API (GNOME Keyring, KWallet) elsewhere. On Windows, which has
+// no command line tool that can read secrets back, the generic credential "`service`:`account`" is
+// read from Credential Manager directly.
+func lookupKeyring(ctx context.Context, service string, account string) (string, error) {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "windows":
+		return readCredentialManager(service + ":" + account)
+	case "plan9", "js", "wasip1":
+		return "", ErrKeyringUnsupported
+	default:
+		cmd = exec.CommandContext(ctx, "secret-tool", "lookup", "service", service, "username", account)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Decodes the secret of a Windows credential. `cmdkey` and the Control Panel store passwords as
+// UTF-16, while other tools store the bytes of the secret as is; an API key is ASCII, so a blob with
+// a zero in every other byte is taken to be UTF-16.
+func decodeCredentialBlob(blob []byte) string {
+	if len(blob)%2 != 0 {
+		return string(blob)
+	}
+
+	units := make([]uint16, 0, len(blob)/2)
+
+	for i := 0; i < len(blob); i += 2 {
+		if blob[i+1] != 0 {
+			return string(blob)
+		}
+
+		units = append(units, uint16(blob[i]))
+	}
+
+	return string(utf16.Decode(units))
+}
+
+// Returns the API key stored in the OS keyring for `login`, looking it up if it hasn't been
+// already.
+func (p *Provider) readKeyringAPIKey(ctx context.Context, login string) (string, error) {
+	p.keyringAPIKeyMtx.Lock()
+	defer p.keyringAPIKeyMtx.Unlock()
+
+	if p.keyringAPIKey != "" {
+		return p.keyringAPIKey, nil
+	}
+
+	if login == "" {
+		return "", fmt.Errorf("Failed to read API key from keyring: no login to look it up by")
+	}
+
+	apiKey, err := keyringLookup(ctx, p.KeyringService, login)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to read API key from keyring: %w", err)
+	}
+
+	if apiKey == "" {
+		return "", fmt.Errorf("Failed to read API key from keyring: no key stored for %q in service %q", login, p.KeyringService)
+	}
+
+	p.keyringAPIKey = apiKey
+	return p.keyringAPIKey, nil
+}
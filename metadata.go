@@ -0,0 +1,27 @@
+package nfsn
+
+import (
+	"github.com/libdns/libdns"
+	"github.com/libdns/nfsn/convert"
+)
+
+// RecordScope returns the NFSN scope of a record read from NFSN: "system" for records managed by
+// NFSN itself, which members can't edit, and usually "member" otherwise. Returns an empty string
+// for records that didn't come from NFSN. See `convert.Scope`.
+func RecordScope(record libdns.Record) string {
+	return convert.Scope(record)
+}
+
+// IsSystemRecord returns true if `record` was read from NFSN and is managed by NFSN itself.
+func IsSystemRecord(record libdns.Record) bool {
+	return RecordScope(record) == systemScope
+}
+
+// SameRecord returns true if `a` and `b` are identical apart from the NFSN metadata records read
+// from NFSN carry in their ID, e.g. to check that GetRecords returns a record exactly as it was
+// written. Unlike `Equal`, it doesn't normalize the records first.
+func SameRecord(a libdns.Record, b libdns.Record) bool {
+	a.ID = ""
+	b.ID = ""
+	return a == b
+}
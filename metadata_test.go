@@ -0,0 +1,52 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRecordScope(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+			{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"}
+		]`))
+	})
+
+	records, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records but got %d", len(records))
+	}
+
+	if RecordScope(records[0]) != "system" || !IsSystemRecord(records[0]) {
+		t.Errorf("Expected a system record but got %+v", records[0])
+	}
+
+	if RecordScope(records[1]) != "member" || IsSystemRecord(records[1]) {
+		t.Errorf("Expected a member record but got %+v", records[1])
+	}
+
+	if scope := RecordScope(libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1"}); scope != "" {
+		t.Errorf("Expected no scope but got '%s'", scope)
+	}
+
+	// The metadata doesn't stop the record matching the one that was written
+	written := libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: time.Hour}
+
+	if records[1] == written || !SameRecord(records[1], written) {
+		t.Errorf("Expected %+v to be the same record as %+v apart from its metadata", records[1], written)
+	}
+
+	if written.TTL = time.Minute; SameRecord(records[1], written) {
+		t.Errorf("Expected records with different TTLs not to be the same")
+	}
+}
@@ -0,0 +1,49 @@
+package nfsn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/nfsn/convert"
+)
+
+// Returns `name` relative to `zone`. A name with a trailing dot is fully qualified, and must be the
+// zone itself (giving "") or a name within it; other names are already relative and are returned
+// as they are. Without this, NFSN would treat "www.example.com." as "www.example.com.example.com".
+func relativeName(name string, zone string) (string, error) {
+	if !strings.HasSuffix(name, ".") {
+		return name, nil
+	}
+
+	asciiName := strings.ToLower(convert.ASCIIName(name))
+	asciiZone := strings.ToLower(convert.ASCIIName(strings.TrimSuffix(zone, ".") + "."))
+
+	if asciiName == asciiZone {
+		return "", nil
+	}
+
+	if strings.HasSuffix(asciiName, "."+asciiZone) {
+		return strings.TrimSuffix(asciiName, "."+asciiZone), nil
+	}
+
+	return "", fmt.Errorf("Name %q is not in zone %q", name, zone)
+}
+
+// Returns a copy of `records` with names relative to `zone` (see `relativeName`).
+func relativeRecords(zone string, records []libdns.Record) ([]libdns.Record, error) {
+	relative := make([]libdns.Record, len(records))
+
+	for i, record := range records {
+		name, err := relativeName(record.Name, zone)
+
+		if err != nil {
+			return nil, err
+		}
+
+		record.Name = name
+		relative[i] = record
+	}
+
+	return relative, nil
+}
@@ -0,0 +1,168 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRelativeName(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"www", "www"},
+		{"@", "@"},
+		{"www.example.com.", "www"},
+		{"_acme-challenge.WWW.Example.COM.", "_acme-challenge.www"},
+		{"example.com.", ""},
+		{"bücher.example.com.", "xn--bcher-kva"},
+	}
+
+	for _, c := range cases {
+		name, err := relativeName(c.name, "example.com.")
+
+		if err != nil {
+			t.Errorf("%s: Unexpected error %v", c.name, err)
+		}
+
+		if name != c.expected {
+			t.Errorf("Expected '%s' but got '%s'", c.expected, name)
+		}
+	}
+
+	for _, name := range []string{"www.example.net.", "notexample.com."} {
+		if _, err := relativeName(name, "example.com."); err == nil {
+			t.Errorf("%s: Expected an error", name)
+		}
+	}
+}
+
+func TestAbsoluteNames(t *testing.T) {
+	var names []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		names = append(names, r.PostForm.Get("name"))
+		w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600}, {"name": "", "type": "A", "data": "192.0.2.2", "ttl": 3600}]`))
+	})
+
+	_, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{{Type: "A", Name: "www.example.com.", Value: "192.0.2.1"}})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if names[0] != "www" {
+		t.Errorf("Expected 'www' but got '%s'", names[0])
+	}
+
+	_, err = p.AppendRecords(context.Background(), "example.com.", []libdns.Record{{Type: "A", Name: "www.example.net.", Value: "192.0.2.1"}})
+
+	if err == nil {
+		t.Errorf("Expected an error for a name outside the zone")
+	}
+
+	p.AbsoluteNames = true
+	records, _ := p.GetRecords(context.Background(), "example.com.")
+
+	if len(records) != 2 || records[0].Name != "www.example.com." || records[1].Name != "example.com." {
+		t.Errorf("Expected fully qualified names but got %+v", records)
+	}
+}
+
+func TestUnicodeNames(t *testing.T) {
+	var paths []string
+	var names []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		paths = append(paths, r.URL.Path)
+		names = append(names, r.PostForm.Get("name"))
+		w.Write([]byte(`[{"name": "xn--bcher-kva", "type": "A", "data": "192.0.2.1", "ttl": 3600}]`))
+	})
+
+	_, err := p.AppendRecords(context.Background(), "例え.jp.", []libdns.Record{{Type: "A", Name: "bücher", Value: "192.0.2.1"}})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if paths[0] != "/dns/xn--r8jz45g.jp/addRR" || names[0] != "xn--bcher-kva" {
+		t.Errorf("Expected punycode names but got '%s', '%s'", paths[0], names[0])
+	}
+
+	records, _ := p.GetRecords(context.Background(), "例え.jp.")
+
+	if len(records) != 1 || records[0].Name != "xn--bcher-kva" {
+		t.Errorf("Expected the A-label but got %+v", records)
+	}
+
+	p.UnicodeNames = true
+	records, _ = p.GetRecords(context.Background(), "例え.jp.")
+
+	if len(records) != 1 || records[0].Name != "bücher" {
+		t.Errorf("Expected the U-label but got %+v", records)
+	}
+}
+
+func TestWildcardRecords(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[
+				{"name": "*", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+				{"name": "*.sub", "type": "A", "data": "192.0.2.2", "ttl": 3600, "scope": "member"},
+				{"name": "sub", "type": "A", "data": "192.0.2.3", "ttl": 3600, "scope": "member"}
+			]`))
+		case "/dns/example.com/replaceRRSet":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("name")+" "+r.PostForm.Get("data"))
+		}
+	})
+
+	records, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(records) != 3 || records[0].Name != "*" || records[1].Name != "*.sub" {
+		t.Fatalf("Unexpected records %+v", records)
+	}
+
+	_, err = p.SetRecords(context.Background(), "example.com.", []libdns.Record{
+		{Type: "A", Name: `\*.sub.example.com.`, Value: "192.0.2.4"},
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Only the wildcard is removed, not the names it covers
+	_, err = p.DeleteRecords(context.Background(), "example.com.", []libdns.Record{{Type: "A", Name: `\042`}})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"replaceRR *.sub 192.0.2.4", "removeRR * 192.0.2.1"}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+
+	_, err = p.AppendRecords(context.Background(), "example.com.", []libdns.Record{{Type: "A", Name: "sub.*", Value: "192.0.2.5"}})
+
+	if err == nil {
+		t.Errorf("Expected an error for a misplaced wildcard")
+	}
+}
@@ -0,0 +1,17 @@
+package nfsn
+
+import (
+	"github.com/libdns/libdns"
+	"github.com/libdns/nfsn/convert"
+)
+
+// Normalize returns `record` in the canonical form NFSN stores it in, so that a record a caller
+// intends to write can be compared with one read from NFSN. See `convert.Normalize`.
+func Normalize(record libdns.Record) libdns.Record {
+	return convert.Normalize(record)
+}
+
+// Equal returns true if `a` and `b` are the same record once normalized (see `Normalize`).
+func Equal(a libdns.Record, b libdns.Record) bool {
+	return convert.Equal(a, b)
+}
@@ -0,0 +1,65 @@
+package nfsn
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a Provider created with NewProvider.
+type Option func(*Provider)
+
+// NewProvider creates a Provider for the NFSN member `login` with API key `apiKey`, configured with
+// `opts`. A zero value Provider with Login and APIKey set works just as well; NewProvider offers a
+// stable way to set options that aren't exposed as fields.
+func NewProvider(login string, apiKey string, opts ...Option) *Provider {
+	p := &Provider{
+		Login:  login,
+		APIKey: apiKey,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithHTTPClient makes API requests with `client`. Equivalent to setting `Provider.HTTPClient`.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.HTTPClient = client
+	}
+}
+
+// WithBaseURL sends API requests to `baseURL` rather than https://api.nearlyfreespeech.net, e.g. to
+// go through a proxy or to a test server.
+func WithBaseURL(baseURL string) Option {
+	return func(p *Provider) {
+		p.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithTimeout sets the timeout of the default HTTP client, which is otherwise 30 seconds. Has no
+// effect on a client supplied with WithHTTPClient.
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+// WithLogger logs diagnostic messages, such as retries and events, to `logger`.
+func WithLogger(logger *log.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger
+	}
+}
+
+// Logs a diagnostic message, if a logger is configured.
+func (p *Provider) logf(format string, args ...interface{}) {
+	if p.logger != nil {
+		p.logger.Print(p.redactSecrets(fmt.Sprintf(format, args...)))
+	}
+}
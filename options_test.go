@@ -0,0 +1,97 @@
+package nfsn
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewProvider(t *testing.T) {
+	setRetryDelay(t, 0)
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	p := NewProvider("testuser", "p3kxmRKf9dk3l6ls",
+		WithHTTPClient(server.Client()),
+		WithBaseURL(server.URL+"/"),
+		WithLogger(log.New(&logs, "", 0)),
+	)
+
+	if p.Login != "testuser" || p.APIKey != "p3kxmRKf9dk3l6ls" {
+		t.Errorf("Unexpected credentials %v", p)
+	}
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests to the base URL but got %d", requests)
+	}
+
+	if !strings.Contains(logs.String(), "Retrying POST") {
+		t.Errorf("Expected the retry to be logged but got '%s'", logs.String())
+	}
+
+	p = NewProvider("testuser", "p3kxmRKf9dk3l6ls", WithTimeout(5*time.Second))
+
+	if client := p.ensureClient(); client.Timeout != 5*time.Second {
+		t.Errorf("Expected timeout 5s but got %s", client.Timeout)
+	}
+}
+
+func TestClose(t *testing.T) {
+	closed := make(chan struct{}, 1)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	p := NewProvider("testuser", "p3kxmRKf9dk3l6ls", WithBaseURL(server.URL))
+
+	if _, err := p.GetRecords(context.Background(), "example.com."); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected the idle connection to be closed")
+	}
+
+	// Still usable after Close
+	if _, err := p.GetRecords(context.Background(), "example.com."); err != nil {
+		t.Errorf("Unexpected error after Close %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package nfsn
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// Operation is a single NFSN API call made to change a zone, as returned by PlanSetRecords and
+// PlanSync.
+type Operation struct {
+	// The API verb, e.g. "addRR" or "removeRR"
+	Verb string
+
+	// The parameters the verb is called with
+	Parameters url.Values
+
+	// The records the call adds, replaces, or removes. A `replaceRRSet` call covers every record in
+	// its set; other verbs cover a single record.
+	Records []libdns.Record
+}
+
+// PlanSetRecords returns the API calls SetRecords would make for `records`, in order, without
+// making them. Like SetRecords it validates the records, and it may probe the API to find out
+// whether `replaceRRSet` is supported (see `Capabilities`). The calls a rollback would make aren't
+// included, since they depend on which call fails.
+func (p *Provider) PlanSetRecords(ctx context.Context, zone string, records []libdns.Record) ([]Operation, error) {
+	records, err := relativeRecords(zone, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = p.withZoneMinTTL(ctx, zone)
+	_, err = p.validateRecords(ctx, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities, err := p.Capabilities(ctx, zone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.replaceOperations(ctx, records, capabilities.ReplaceRRSet)
+}
+
+// PlanSync returns the API calls SyncZone would make for `desired` and `opts`, in order, without
+// making them. The zone is read to work out the differences, as SyncZone does.
+func (p *Provider) PlanSync(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) ([]Operation, error) {
+	plan, err := p.planSync(ctx, zone, desired, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.syncOperations(p.withZoneMinTTL(ctx, zone), plan)
+}
+
+// Returns a `verb` operation for each of `records`, grouped into (name, type) sets (see
+// `groupRecords`). Returns an error if any record is invalid.
+func (p *Provider) recordOperations(ctx context.Context, verb string, records []libdns.Record) ([]Operation, error) {
+	if verb == "removeRR" {
+		ctx = withRemoval(ctx)
+	}
+
+	allParams, err := p.validateRecords(ctx, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []Operation
+
+	for _, set := range groupRecords(records, allParams) {
+		for i, record := range set.records {
+			ops = append(ops, Operation{Verb: verb, Parameters: set.params[i], Records: []libdns.Record{record}})
+		}
+	}
+
+	return ops, nil
+}
+
+// Returns the operations that replace each (name, type) set in `records`: a single `replaceRRSet`
+// per set if `replaceRRSet` is true, otherwise `replaceRR` for the first record of each set and
+// `addRR` for the rest. Returns an error if any record is invalid.
+func (p *Provider) replaceOperations(ctx context.Context, records []libdns.Record, replaceRRSet bool) ([]Operation, error) {
+	allParams, err := p.validateRecords(ctx, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []Operation
+
+	for _, set := range groupRecords(records, allParams) {
+		if replaceRRSet {
+			params := url.Values{}
+
+			for k, v := range set.params[0] {
+				params[k] = append([]string(nil), v...)
+			}
+
+			for _, recordParams := range set.params[1:] {
+				params.Add("data", recordParams.Get("data"))
+			}
+
+			ops = append(ops, Operation{Verb: "replaceRRSet", Parameters: params, Records: set.records})
+			continue
+		}
+
+		for i, record := range set.records {
+			verb := "addRR"
+
+			if i == 0 {
+				verb = "replaceRR"
+			}
+
+			ops = append(ops, Operation{Verb: verb, Parameters: set.params[i], Records: []libdns.Record{record}})
+		}
+	}
+
+	return ops, nil
+}
+
+// Returns the operations that carry out `plan`: removals, then replacements, then additions.
+func (p *Provider) syncOperations(ctx context.Context, plan syncPlan) ([]Operation, error) {
+	removals, err := p.recordOperations(ctx, "removeRR", plan.remove)
+
+	if err != nil {
+		return nil, err
+	}
+
+	replacements, err := p.replaceOperations(ctx, plan.replace, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	additions, err := p.recordOperations(ctx, "addRR", plan.add)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(removals, replacements...), additions...), nil
+}
+
+func (p *Provider) maxConcurrentRequests() int {
+	if p.MaxConcurrentRequests > 0 {
+		return p.MaxConcurrentRequests
+	}
+
+	return 1
+}
+
+// Makes the API call for each of `ops`, up to `MaxConcurrentRequests` at a time. Calls for records
+// with the same name are made one at a time in the order given, since e.g. a CNAME can only be
+// added once the name's other records are removed; calls for different names may be made in any
+// order. After the first failure no more calls are started, unless the context was prepared by
+// `withContinueOnError`, in which case every call is made. Returns the records covered by the
+// calls that succeeded, in the order of `ops`, and if any call failed a *BatchError with a result
+// for each record.
+func (p *Provider) applyOperations(ctx context.Context, zone string, ops []Operation) ([]libdns.Record, error) {
+	ctx = p.withRetryBudget(ctx)
+	continueOnError, _ := ctx.Value(continueOnErrorKey{}).(bool)
+	chains := operationChains(ops)
+	workers := p.maxConcurrentRequests()
+
+	if workers > len(chains) {
+		workers = len(chains)
+	}
+
+	attempted := make([]bool, len(ops))
+	errs := make([]error, len(ops))
+	failed := false
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	next := make(chan []int)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for chain := range next {
+				for _, i := range chain {
+					mtx.Lock()
+					stop := failed && !continueOnError
+					mtx.Unlock()
+
+					if stop {
+						break
+					}
+
+					err := p.applyOperation(ctx, zone, ops[i])
+
+					mtx.Lock()
+					attempted[i] = true
+					errs[i] = err
+					failed = failed || err != nil
+					mtx.Unlock()
+
+					if err != nil && !continueOnError {
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	for _, chain := range chains {
+		next <- chain
+	}
+
+	close(next)
+	wg.Wait()
+
+	var successfulRecords []libdns.Record
+	var results []RecordResult
+
+	for i, op := range ops {
+		if attempted[i] && errs[i] == nil {
+			successfulRecords = append(successfulRecords, op.Records...)
+		}
+
+		for _, record := range op.Records {
+			results = append(results, RecordResult{Record: record, Attempted: attempted[i], Err: errs[i]})
+		}
+	}
+
+	if !failed {
+		return successfulRecords, nil
+	}
+
+	return successfulRecords, &BatchError{Results: results}
+}
+
+type continueOnErrorKey struct{}
+
+// Returns a context with which `applyOperations` makes every call even if some fail, if
+// `ContinueOnError` is set.
+func (p *Provider) withContinueOnError(ctx context.Context) context.Context {
+	if !p.ContinueOnError {
+		return ctx
+	}
+
+	return context.WithValue(ctx, continueOnErrorKey{}, true)
+}
+
+// Splits `ops` into chains of operations on the same record name, each in the order given, ordered
+// by their first operation. Returns the indexes of the operations.
+func operationChains(ops []Operation) [][]int {
+	var chains [][]int
+	chainForName := make(map[string]int)
+
+	for i, op := range ops {
+		name := ""
+
+		if len(op.Records) > 0 {
+			name = strings.ToLower(Normalize(op.Records[0]).Name)
+		}
+
+		chain, ok := chainForName[name]
+
+		if !ok {
+			chain = len(chains)
+			chainForName[name] = chain
+			chains = append(chains, nil)
+		}
+
+		chains[chain] = append(chains[chain], i)
+	}
+
+	return chains
+}
+
+// Makes the API call for `op`, within `OperationTimeout`.
+func (p *Provider) applyOperation(ctx context.Context, zone string, op Operation) error {
+	if op.Verb != "removeRR" {
+		for _, record := range op.Records {
+			p.checkTTL(ctx, record)
+		}
+	}
+
+	if p.OperationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.OperationTimeout)
+		defer cancel()
+	}
+
+	_, err := p.makeRequest(ctx, "POST", p.uriForZone(zone, op.Verb), strings.NewReader(op.Parameters.Encode()))
+	return err
+}
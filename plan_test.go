@@ -0,0 +1,277 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Formats `ops` as "verb type name data..." strings
+func describeOperations(ops []Operation) []string {
+	var described []string
+
+	for _, op := range ops {
+		description := op.Verb + " " + op.Parameters.Get("type") + " " + op.Parameters.Get("name")
+
+		for _, data := range op.Parameters["data"] {
+			description += " " + data
+		}
+
+		described = append(described, description)
+	}
+
+	return described
+}
+
+func TestPlanSetRecords(t *testing.T) {
+	replaceRRSetStatus := http.StatusNotFound
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.URL.Path == "/dns/example.com/replaceRRSet" {
+			w.WriteHeader(replaceRRSetStatus)
+		}
+	})
+
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.1"},
+		{Type: "MX", Name: "", Value: "mail.example.com.", Priority: 10},
+		{Type: "A", Name: "www", Value: "192.0.2.2"},
+	}
+
+	ops, err := p.PlanSetRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"replaceRR A www 192.0.2.1",
+		"addRR A www 192.0.2.2",
+		"replaceRR MX  10 mail.example.com.",
+	}
+
+	if !reflect.DeepEqual(describeOperations(ops), expected) {
+		t.Errorf("Expected %v but got %v", expected, describeOperations(ops))
+	}
+
+	// Only the capability probe was made
+	if requests != 1 {
+		t.Errorf("Expected 1 request but got %d", requests)
+	}
+
+	p.capabilities = nil
+	replaceRRSetStatus = http.StatusBadRequest
+	ops, err = p.PlanSetRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected = []string{
+		"replaceRRSet A www 192.0.2.1 192.0.2.2",
+		"replaceRRSet MX  10 mail.example.com.",
+	}
+
+	if !reflect.DeepEqual(describeOperations(ops), expected) {
+		t.Errorf("Expected %v but got %v", expected, describeOperations(ops))
+	}
+
+	if len(ops[0].Records) != 2 {
+		t.Errorf("Expected the operation to cover 2 records but got %+v", ops[0].Records)
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	p, mutations := newSyncTestProvider(t)
+
+	desired := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 10 * time.Minute},
+		{Type: "MX", Name: "", Value: "mail1.example.com.", Priority: 10, TTL: time.Hour},
+		{Type: "MX", Name: "", Value: "mail2.example.com.", Priority: 20, TTL: time.Hour},
+		{Type: "TXT", Name: "", Value: "v=spf1 -all"},
+	}
+
+	ops, err := p.PlanSync(context.Background(), "example.com.", desired, SyncOptions{})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"removeRR TXT  stale",
+		"removeRR CNAME old www.example.com.",
+		"replaceRR A www 192.0.2.1",
+		"addRR MX  20 mail2.example.com.",
+	}
+
+	if !reflect.DeepEqual(describeOperations(ops), expected) {
+		t.Errorf("Expected %v but got %v", expected, describeOperations(ops))
+	}
+
+	if len(*mutations) != 0 {
+		t.Errorf("Expected no changes but got %v", *mutations)
+	}
+}
+
+func TestApplyOperationsConcurrently(t *testing.T) {
+	var mtx sync.Mutex
+	active := 0
+	maxActive := 0
+	requestsByName := make(map[string][]string)
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		mtx.Lock()
+		active++
+
+		if active > maxActive {
+			maxActive = active
+		}
+
+		name := r.PostForm.Get("name")
+		requestsByName[name] = append(requestsByName[name], r.PostForm.Get("data"))
+		mtx.Unlock()
+
+		// Give the other workers a chance to overlap with this request
+		time.Sleep(20 * time.Millisecond)
+
+		mtx.Lock()
+		active--
+		mtx.Unlock()
+	})
+
+	var records []libdns.Record
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		records = append(records,
+			libdns.Record{Type: "TXT", Name: name, Value: "first"},
+			libdns.Record{Type: "TXT", Name: name, Value: "second"},
+		)
+	}
+
+	// Requests are made one at a time by default
+	_, err := p.AppendRecords(context.Background(), "example.com.", records[:4])
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if maxActive != 1 {
+		t.Errorf("Expected 1 request at once but got %d", maxActive)
+	}
+
+	maxActive = 0
+	requestsByName = make(map[string][]string)
+	p.MaxConcurrentRequests = 3
+	appended, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !reflect.DeepEqual(appended, records) {
+		t.Errorf("Expected %+v but got %+v", records, appended)
+	}
+
+	if maxActive < 2 || maxActive > 3 {
+		t.Errorf("Expected between 2 and 3 requests at once but got %d", maxActive)
+	}
+
+	for name, data := range requestsByName {
+		if !reflect.DeepEqual(data, []string{"first", "second"}) {
+			t.Errorf("Expected the records for %q in order but got %v", name, data)
+		}
+	}
+}
+
+func TestApplyOperationsStopsAfterFailure(t *testing.T) {
+	var mtx sync.Mutex
+	var requests []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		mtx.Lock()
+		requests = append(requests, r.PostForm.Get("name"))
+		mtx.Unlock()
+
+		if r.PostForm.Get("name") == "a" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	p.MaxConcurrentRequests = 2
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "a", Value: "first"},
+		{Type: "TXT", Name: "a", Value: "second"},
+		{Type: "TXT", Name: "b", Value: "first"},
+	}
+
+	appended, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	// The failed name's later records are never sent, but the other name may already be in flight
+	sentA := 0
+
+	for _, name := range requests {
+		if name == "a" {
+			sentA++
+		}
+	}
+
+	if sentA != 1 {
+		t.Errorf("Expected no more requests for %q after it failed but got %v", "a", requests)
+	}
+
+	for _, record := range appended {
+		if record.Name != "b" {
+			t.Errorf("Unexpected appended record %+v", record)
+		}
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		if r.PostForm.Get("name") == "slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+	})
+	p.OperationTimeout = 50 * time.Millisecond
+	p.ContinueOnError = true
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "slow", Value: "a"},
+		{Type: "TXT", Name: "fast", Value: "b"},
+	}
+
+	appended, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded but got %v", err)
+	}
+
+	if !reflect.DeepEqual(appended, records[1:]) {
+		t.Errorf("Expected %+v but got %+v", records[1:], appended)
+	}
+}
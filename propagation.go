@@ -0,0 +1,252 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/nfsn/convert"
+)
+
+// Default time between checks in WaitForPropagation
+const defaultPropagationInterval = 5 * time.Second
+
+// PropagationOptions controls WaitForPropagation.
+type PropagationOptions struct {
+	// The time between checks. Defaults to 5 seconds.
+	Interval time.Duration
+
+	// The nameservers to check, as host names or addresses with an optional port. Defaults to the
+	// zone's NS records as listed by NFSN, i.e. its authoritative nameservers.
+	Nameservers []string
+
+	// Additional resolvers, such as public recursive resolvers, that must also return the record.
+	// Recursive resolvers cache answers, so a record that was changed rather than added may not be
+	// visible through them until the old record's TTL expires.
+	Resolvers []string
+}
+
+// Looks up the records of type `rType` for the fully qualified `name` from the DNS server at
+// `server` (host:port). Swapped out in tests.
+var propagationLookup = lookupRecords
+
+// WaitForPropagation polls the zone's nameservers until every one of them returns `record`, or
+// until `ctx` is done, e.g. so that an ACME client can wait for a challenge record to be visible
+// before asking for it to be checked. The record's value, and priority where it has one, are
+// compared; its TTL isn't.
+//
+// A, AAAA, CNAME, MX, NS, SRV, and TXT records can be checked. Returns nil once the record is
+// visible, or an error wrapping the context's error, naming the servers that didn't return it.
+func (p *Provider) WaitForPropagation(ctx context.Context, zone string, record libdns.Record, opts PropagationOptions) error {
+	name, err := relativeName(record.Name, zone)
+
+	if err != nil {
+		return err
+	}
+
+	record.Name = name
+	record = Normalize(record)
+
+	switch record.Type {
+	case "A", "AAAA", "CNAME", "MX", "NS", "SRV", "TXT":
+	default:
+		return fmt.Errorf("Can't check the propagation of %s records", record.Type)
+	}
+
+	servers := opts.Nameservers
+
+	if len(servers) == 0 {
+		servers, err = p.zoneNameservers(ctx, zone)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	servers = append(append([]string(nil), servers...), opts.Resolvers...)
+	interval := opts.Interval
+
+	if interval <= 0 {
+		interval = defaultPropagationInterval
+	}
+
+	fqdn := convert.ASCIIName(strings.TrimSuffix(libdns.AbsoluteName(record.Name, zone), ".") + ".")
+	pending := servers
+
+	for {
+		var stillPending []string
+		var lastErr error
+
+		for _, server := range pending {
+			visible, err := recordVisible(ctx, withDefaultPort(server), fqdn, record)
+
+			if err != nil {
+				lastErr = err
+			}
+
+			if !visible {
+				stillPending = append(stillPending, server)
+			}
+		}
+
+		if len(stillPending) == 0 {
+			return nil
+		}
+
+		pending = stillPending
+		p.logf("%s record %q is not yet visible on %s", record.Type, fqdn, strings.Join(pending, ", "))
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%s record %q was not visible on %s: %w (last lookup error: %v)", record.Type, fqdn, strings.Join(pending, ", "), ctx.Err(), lastErr)
+			}
+
+			return fmt.Errorf("%s record %q was not visible on %s: %w", record.Type, fqdn, strings.Join(pending, ", "), ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Returns the names of the zone's authoritative nameservers, from its NS records at the apex.
+func (p *Provider) zoneNameservers(ctx context.Context, zone string) ([]string, error) {
+	records, err := p.getRecords(ctx, zone, "@", "NS")
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return nil, err
+	}
+
+	var servers []string
+
+	for _, record := range records {
+		if record.Name == "" || record.Name == "@" {
+			servers = append(servers, record.Value)
+		}
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("Zone %s has no NS records to check", zone)
+	}
+
+	return servers, nil
+}
+
+// Returns `server` with port 53 if it doesn't specify a port.
+func withDefaultPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+
+	return net.JoinHostPort(strings.TrimSuffix(server, "."), "53")
+}
+
+// Returns true if `server` returns `record` (normalized) for `fqdn`.
+func recordVisible(ctx context.Context, server string, fqdn string, record libdns.Record) (bool, error) {
+	found, err := propagationLookup(ctx, server, fqdn, record.Type)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, candidate := range found {
+		candidate.Name = record.Name
+		candidate.TTL = record.TTL
+
+		if Equal(candidate, record) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Looks up the records of type `rType` for `fqdn` directly from `server`, bypassing the system
+// resolver and any cache. Returns them in the form of libdns.Record values and priorities.
+func lookupRecords(ctx context.Context, server string, fqdn string, rType string) ([]libdns.Record, error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+
+	var records []libdns.Record
+
+	switch rType {
+	case "A", "AAAA":
+		network := "ip4"
+
+		if rType == "AAAA" {
+			network = "ip6"
+		}
+
+		addrs, err := resolver.LookupNetIP(ctx, network, fqdn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			records = append(records, libdns.Record{Type: rType, Value: addr.Unmap().String()})
+		}
+	case "CNAME":
+		target, err := resolver.LookupCNAME(ctx, fqdn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, libdns.Record{Type: rType, Value: target})
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, fqdn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mx := range mxs {
+			records = append(records, libdns.Record{Type: rType, Value: mx.Host, Priority: uint(mx.Pref)})
+		}
+	case "NS":
+		nss, err := resolver.LookupNS(ctx, fqdn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range nss {
+			records = append(records, libdns.Record{Type: rType, Value: ns.Host})
+		}
+	case "SRV":
+		_, srvs, err := resolver.LookupSRV(ctx, "", "", fqdn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, srv := range srvs {
+			records = append(records, libdns.Record{Type: rType, Value: strconv.Itoa(int(srv.Port)) + " " + srv.Target, Priority: uint(srv.Priority), Weight: uint(srv.Weight)})
+		}
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, fqdn)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, txt := range txts {
+			records = append(records, libdns.Record{Type: rType, Value: txt})
+		}
+	default:
+		return nil, fmt.Errorf("Can't look up %s records", rType)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,78 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestWaitForPropagation(t *testing.T) {
+	original := propagationLookup
+	t.Cleanup(func() { propagationLookup = original })
+
+	lookups := make(map[string]int)
+	var names []string
+
+	// The record appears on the first nameserver straight away, and on the second after two checks
+	propagationLookup = func(ctx context.Context, server string, fqdn string, rType string) ([]libdns.Record, error) {
+		lookups[server]++
+		names = append(names, rType+" "+fqdn)
+
+		if server == "ns.phx1.nearlyfreespeech.net:53" || lookups[server] > 2 {
+			return []libdns.Record{{Type: "TXT", Value: "token"}, {Type: "TXT", Value: "other"}}, nil
+		}
+
+		return []libdns.Record{{Type: "TXT", Value: "other"}}, nil
+	}
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+			{"name": "", "type": "NS", "data": "ns.phx2.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"}
+		]`))
+	})
+
+	record := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+	err := p.WaitForPropagation(context.Background(), "example.com.", record, PropagationOptions{Interval: time.Millisecond})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := map[string]int{"ns.phx1.nearlyfreespeech.net:53": 1, "ns.phx2.nearlyfreespeech.net:53": 3}
+
+	if !reflect.DeepEqual(lookups, expected) {
+		t.Errorf("Expected %v but got %v", expected, lookups)
+	}
+
+	sort.Strings(names)
+
+	if names[0] != "TXT _acme-challenge.example.com." {
+		t.Errorf("Expected '_acme-challenge.example.com.' to be looked up but got %v", names)
+	}
+
+	// Never visible on the resolver
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	record.Value = "missing"
+	err = p.WaitForPropagation(ctx, "example.com.", record, PropagationOptions{Interval: time.Millisecond, Nameservers: []string{"192.0.2.53"}, Resolvers: []string{"198.51.100.53:5353"}})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error but got %v", err)
+	}
+
+	if _, ok := lookups["198.51.100.53:5353"]; !ok {
+		t.Errorf("Expected the resolver to be checked but got %v", lookups)
+	}
+
+	if err = p.WaitForPropagation(context.Background(), "example.com.", libdns.Record{Type: "CAA", Value: "0 issue letsencrypt.org"}, PropagationOptions{}); err == nil {
+		t.Errorf("Expected an error for an unsupported type")
+	}
+}
@@ -8,49 +8,283 @@ import (
 	"crypto/rand"
 	"crypto/sha1"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/libdns/libdns"
+	"github.com/libdns/nfsn/convert"
 )
 
 const apiBase = "https://api.nearlyfreespeech.net"
 const authHeader = "X-NFSN-Authentication"
 
-// NFSN enforces a minimum TTL of 3 minutes
-const minimumTTL = 180 * time.Second
+// Version of this package, reported in the User-Agent header
+const version = "0.1.0"
+const defaultUserAgent = "libdns-nfsn/" + version
+
+// Timeout for requests made with the default HTTP client
+const defaultTimeout = 30 * time.Second
+
+// Scope of records managed by NFSN itself, which members can't edit
+const systemScope = "system"
+
+// NFSN's usual minimum TTL of 3 minutes. Used unless `Provider.MinTTL` or a per-call override
+// (see `WithMinTTL`) says otherwise, or NFSN reports a different minimum for the zone.
+const minimumTTL = convert.MinimumTTL
 
 // Constants used for API salt generation
-const saltChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijjklmnopqrstuvwxyz0123456789"
+const saltChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
 const saltLen = 16
 
+// Random bytes at or above this are discarded when generating a salt, so that every character is
+// equally likely
+const saltByteLimit = 256 - 256%len(saltChars)
+
 // Provider facilitates DNS record manipulation with nearlyfreespeech.net
 type Provider struct {
-	// NFSN Member Login.
+	// NFSN Member Login. If empty, the NFSN_LOGIN environment variable is used instead.
 	Login string `json:"login,omitempty"`
 
 	// NFSN API Key. API Keys can be generated from the "Profile" tab in the NFSN member interface.
+	// If empty, the NFSN_API_KEY environment variable is used instead. See also `SetAPIKeyBytes`.
 	APIKey string `json:"api_key,omitempty"`
 
+	// Path to a file containing the NFSN API Key, used if APIKey is empty. Allows the key to be
+	// provided as a mounted secret. The file is read on first use and again if NFSN rejects the key.
+	APIKeyFile string `json:"api_key_file,omitempty"`
+
+	// Name of an OS keyring service (macOS Keychain, the Secret Service API, or Windows Credential
+	// Manager) that holds the NFSN API Key under the login name, used if APIKey and APIKeyFile are
+	// empty. The key is looked up on first use.
+	KeyringService string `json:"keyring_service,omitempty"`
+
+	// Optional source of credentials, consulted for every request. If set, Login, APIKey,
+	// APIKeyFile, and KeyringService are ignored. Allows keys to be rotated or fetched from a secret store.
+	CredentialSource CredentialSource `json:"-"`
+
+	// If true, the NFSN_LOGIN and NFSN_API_KEY environment variables are never used.
+	DisableEnvCredentials bool `json:"disable_env_credentials,omitempty"`
+
+	// Optional replacement for the default X-NFSN-Authentication signing of requests. If set, the
+	// credentials above are not used.
+	AuthSigner AuthSigner `json:"-"`
+
+	// Optional HTTP client to make API requests with, e.g. to use a custom transport or proxy. If
+	// nil, a client with a 30 second timeout is used.
+	HTTPClient *http.Client `json:"-"`
+
+	// Maximum time a single API request may take, including reading the response. Applies to each
+	// attempt separately, in addition to any deadline on the caller's context, so a hung request
+	// fails (and may be retried) rather than consuming the caller's whole deadline. Zero means no
+	// limit beyond the HTTP client's own timeout.
+	RequestTimeout time.Duration `json:"request_timeout,omitempty"`
+
+	// Maximum time the API call for a single record (or set) in a batch may take, including any
+	// retries. A call that runs out of time fails with an error wrapping context.DeadlineExceeded,
+	// leaving the rest of the caller's deadline for the remaining records, e.g. with
+	// `ContinueOnError`. Zero means no limit beyond the caller's context.
+	OperationTimeout time.Duration `json:"operation_timeout,omitempty"`
+
+	// User-Agent header sent to NFSN. Defaults to "libdns-nfsn/<version>".
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// Optional identifier appended to the User-Agent header sent to NFSN, e.g. "caddy/2.7.6". Allows
+	// programs embedding the provider to identify themselves in NFSN's logs.
+	UserAgentSuffix string `json:"user_agent_suffix,omitempty"`
+
+	// Maximum number of retries shared by all of the requests made by a single AppendRecords,
+	// SetRecords, or DeleteRecords call. Once the budget is exhausted the batch fails on the next
+	// retryable error rather than retrying it. Zero means no batch-wide limit.
+	BatchRetryBudget int `json:"batch_retry_budget,omitempty"`
+
+	// Maximum number of requests a single AppendRecords, SetRecords, DeleteRecords, or SyncZone call
+	// makes at once. Defaults to 1, making requests one at a time. Higher values make large batches
+	// faster, but requests for records with the same name are still made one at a time, in order,
+	// e.g. a set is replaced before records are added to it.
+	MaxConcurrentRequests int `json:"max_concurrent_requests,omitempty"`
+
+	// If true, AppendRecords and DeleteRecords attempt every record even if some fail, rather than
+	// stopping at the first failure, and report each failure in the returned *BatchError. This suits
+	// cleanup, where removing as much as possible matters more than stopping early.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+
+	// Maximum number of times a request is attempted when NFSN responds with a retryable error (429
+	// Too Many Requests, or a 5xx status to a request that only reads). Defaults to 3; set to 1 to
+	// disable retries. Ignored if RetryPolicy is set.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	// Optional replacement for the default retry behavior (see `DefaultRetryPolicy`), e.g. to also
+	// retry writes that failed with a 5xx status. Retries are still limited by `BatchRetryBudget` and the caller's deadline.
+	RetryPolicy RetryPolicy `json:"-"`
+
+	// AAAA records read from NFSN always have their address in canonical (RFC 5952) form, e.g.
+	// "2001:db8::1" rather than "2001:0DB8:0:0::0001". If true, addresses are also canonicalized
+	// before they are written so that records read back compare equal to the records written.
+	CanonicalizeAddresses bool `json:"canonicalize_addresses,omitempty"`
+
+	// Optional hook called with each request after its auth header is added and before it is sent.
+	// It may modify the request, e.g. to add tracing headers. Returning an error aborts the request.
+	// Note that the auth header covers the request path and body, so changing those will cause the
+	// request to be rejected.
+	RequestInterceptor func(*http.Request) error `json:"-"`
+
+	// Optional callback for noteworthy, non-fatal events such as TTL warnings.
+	OnEvent func(Event) `json:"-"`
+
+	// If true, an EventTTLWarning is emitted for each record written with a TTL outside the
+	// recommended bounds for its type (e.g. an NS record with a TTL under an hour).
+	CheckTTLs bool `json:"check_ttls,omitempty"`
+
+	// If true, AppendRecords, SetRecords, and SyncZone re-fetch the (name, type) groups they wrote
+	// after a successful write and return the records as NFSN stored them, including any
+	// normalization NFSN applied such as raised TTLs and lowercased names, rather than the records
+	// that were passed in. Names are returned as GetRecords returns them.
+	VerifyAfterWrite bool `json:"verify_after_write,omitempty"`
+
+	// If true, bulk deletion helpers such as DeleteRecordsWhere may delete records managed by NFSN
+	// (those with the "system" scope). By default such records are never selected for deletion.
+	AllowSystemRecordDeletion bool `json:"allow_system_record_deletion,omitempty"`
+
+	// If true, AppendRecords fetches the zone before writing anything and returns a ConflictError if
+	// a CNAME would share a name with any other record, which NFSN rejects with an unhelpful error.
+	CheckConflicts bool `json:"check_conflicts,omitempty"`
+
+	// The minimum TTL records are written with; lower TTLs are raised to it. Defaults to the
+	// minimum NFSN reports for the zone, or 3 minutes if it can't be read. Can be overridden for a
+	// single call with `WithMinTTL`.
+	MinTTL time.Duration `json:"min_ttl,omitempty"`
+
+	// If true, records with a TTL below the minimum are rejected with ErrTTLBelowMinimum, before
+	// any changes are made, instead of having their TTL silently raised to the minimum.
+	StrictTTL bool `json:"strict_ttl,omitempty"`
+
+	// The TTL records are written with when they don't specify one (a TTL of zero). If unset, the
+	// ttl parameter is left out and NFSN applies its own default.
+	DefaultTTL time.Duration `json:"default_ttl,omitempty"`
+
+	// If true, names of records read from NFSN are fully qualified, e.g. "www.example.com." rather
+	// than "www". Names passed in may always be either.
+	AbsoluteNames bool `json:"absolute_names,omitempty"`
+
+	// Zone and record names may always be given in Unicode, and are converted to punycode for NFSN.
+	// Only lowercasing is applied, not full IDNA mapping, and record names must be composed (NFC).
+	// If true, names of records read from NFSN are converted back to Unicode; otherwise they are
+	// returned as NFSN stores them, e.g. "xn--bcher-kva" rather than "bücher".
+	UnicodeNames bool `json:"unicode_names,omitempty"`
+
+	// If true, TXT values containing control characters or non-ASCII characters, which NFSN doesn't
+	// reliably store as sent, are rejected rather than written.
+	StrictTXT bool `json:"strict_txt,omitempty"`
+
+	// Record types whose values are passed between libdns and NFSN exactly as they are, bypassing the
+	// validation and formatting this package applies to them, e.g. if NFSN accepts a value that is
+	// rejected here. Values are read and written as NFSN's data field, and NFSN's `aux` field is read
+	// as the priority. Types this package doesn't know about are always passed through.
+	PassthroughTypes []string `json:"passthrough_types,omitempty"`
+
+	// If true, SetRecords attempts to undo its changes if it fails part way through. See SetRecords.
+	RollbackOnError bool `json:"rollback_on_error,omitempty"`
+
+	// If set, after this many API requests in a row fail with a network error or a retryable status
+	// (see `MaxAttempts`), requests fail immediately with ErrCircuitOpen for `CircuitBreakerCooldown`
+	// rather than being sent, so that a long-running process doesn't keep hammering a degraded API.
+	// After the cooldown a single trial request is sent, while others still fail with
+	// ErrCircuitOpen; the breaker closes if the trial succeeds and opens again if it fails.
+	CircuitBreakerThreshold int `json:"circuit_breaker_threshold,omitempty"`
+
+	// How long the circuit breaker stays open. Defaults to 1 minute.
+	CircuitBreakerCooldown time.Duration `json:"circuit_breaker_cooldown,omitempty"`
+
 	client    *http.Client
 	clientMtx sync.Mutex
+
+	// Overrides apiBase, see `WithBaseURL`
+	baseURL string
+
+	// Overrides defaultTimeout for the default HTTP client, see `WithTimeout`
+	timeout time.Duration
+
+	// Optional destination for diagnostic logs, see `WithLogger`
+	logger *log.Logger
+
+	// Probed capabilities by zone, see `Capabilities`
+	capabilities    map[string]Capabilities
+	capabilitiesMtx sync.Mutex
+
+	// API key set with SetAPIKeyBytes, which can be zeroed
+	apiKeyBytes    []byte
+	apiKeyBytesMtx sync.Mutex
+
+	// Cached contents of APIKeyFile
+	fileAPIKey    string
+	fileAPIKeyMtx sync.Mutex
+
+	// Cached API key from the OS keyring, see KeyringService
+	keyringAPIKey    string
+	keyringAPIKeyMtx sync.Mutex
+
+	// Recently used (timestamp, salt) pairs, see `reserveSalt`
+	usedSalts    map[usedSalt]struct{}
+	usedSaltsMtx sync.Mutex
+
+	// Offset of NFSN's clock from the local one, see `now`
+	clockSkew    time.Duration
+	clockSkewMtx sync.Mutex
+
+	// Minimum TTLs reported by NFSN by zone, see `withZoneMinTTL`
+	zoneMinTTLs    map[string]time.Duration
+	zoneMinTTLsMtx sync.Mutex
+
+	// In-flight `listRRs` requests by zone, shared by concurrent callers
+	inflight    map[string]*inflightList
+	inflightMtx sync.Mutex
+
+	// Incremented as each write starts and ends, see `noteWrite`
+	writeGeneration uint64
+
+	// Circuit breaker state, see `recordCircuitOutcome`
+	consecutiveFailures int
+	circuitOpenUntil    time.Time
+	circuitProbing      bool
+	circuitMtx          sync.Mutex
+}
+
+// String formats the Provider for display with the API key redacted, so that logging a Provider
+// doesn't leak it. Only a *Provider is redacted: a Provider value (which can't have the method,
+// since it holds mutexes that mustn't be copied) is formatted field by field, API key included, so
+// hold and log Providers by pointer.
+func (p *Provider) String() string {
+	return fmt.Sprintf("{Login:%s APIKey:%s}", p.Login, redact(p.APIKey))
+}
+
+// GoString formats the Provider for `%#v` with the API key redacted. See `String`.
+func (p *Provider) GoString() string {
+	return fmt.Sprintf("&nfsn.Provider{Login:%q, APIKey:%q}", p.Login, redact(p.APIKey))
+}
+
+// Identifies the set of records sharing a name and type
+type rrSetKey struct {
+	name  string
+	rType string
 }
 
-type nfsnRecord struct {
-	Name  string `json:"name,omitempty"`
-	Type  string `json:"type,omitempty"`
-	Data  string `json:"data,omitempty"`
-	TTL   int    `json:"ttl,omitempty"`
-	Scope string `json:"scope,omitempty"`
-	Aux   int    `json:"aux,omitempty"`
+// Returns the key for the set `record` belongs to. The name and type are as NFSN stores them (see
+// `Normalize`), so e.g. "@" and "", or "WWW" and "www", are the same set.
+func rrSetKeyFor(record libdns.Record) rrSetKey {
+	record = Normalize(record)
+	return rrSetKey{record.Name, record.Type}
 }
 
+// A record as NFSN's API represents it
+type nfsnRecord = convert.RR
+
 // The pieces necessary to make a request to create/update a record in NFSN. Differs slightly from
 // the fields in libdns.Record
 type nfsnRecordParameters struct {
@@ -60,71 +294,53 @@ type nfsnRecordParameters struct {
 	TTL  int
 }
 
-func (nRecord nfsnRecord) Record() (libdns.Record, error) {
-	record := libdns.Record{
-		Type:  nRecord.Type,
-		Name:  nRecord.Name,
-		Value: nRecord.Data,
-		TTL:   time.Second * time.Duration(nRecord.TTL),
-	}
-
-	switch nRecord.Type {
-	case "HTTPS":
-	case "MX":
-		record.Priority = uint(nRecord.Aux)
-	case "SRV":
-	case "URI":
-		// Priority is in the 'aux' field from NFSN
-		record.Priority = uint(nRecord.Aux)
-
-		// Data is "weight port target", libdns expects weight in the record
-		parts := strings.SplitN(nRecord.Data, " ", 2)
-
-		if len(parts) != 3 {
-			return libdns.Record{}, fmt.Errorf("%s record %s has incorrect format", nRecord.Name, nRecord.Data)
-		}
-
-		weight, err := strconv.Atoi(parts[0])
-
-		if err != nil {
-			return libdns.Record{}, err
-		}
+// RecordFromNFSN converts the fields of a record as returned by NFSN's `listRRs` API into a
+// libdns.Record. See `convert.RR.Record` for details.
+func RecordFromNFSN(name string, typ string, data string, ttl int, aux int) (libdns.Record, error) {
+	return nfsnRecord{Name: name, Type: typ, Data: data, TTL: ttl, Aux: aux}.Record()
+}
 
-		record.Weight = uint(weight)
-		record.Value = parts[1]
+// Returns the options to convert records with.
+func (p *Provider) convertOptions() convert.Options {
+	return convert.Options{
+		CanonicalizeAddresses: p.CanonicalizeAddresses,
+		StrictTXT:             p.StrictTXT,
+		PassthroughTypes:      p.PassthroughTypes,
 	}
-
-	return record, nil
 }
 
-func toNfsnRecordParameters(record libdns.Record) url.Values {
-	var dataBuilder strings.Builder
+// Returns the parameters to send to NFSN to add, replace, or remove `record` (see
+// `convert.FromLibdns`).
+func (p *Provider) toNfsnRecordParameters(ctx context.Context, record libdns.Record) (url.Values, error) {
+	err := checkReadOnly(record)
 
-	switch record.Type {
-	case "HTTPS":
-	case "MX":
-		dataBuilder.WriteString(fmt.Sprintf("%d ", record.Priority))
-	case "SRV":
-	case "URI":
-		dataBuilder.WriteString(fmt.Sprintf("%d %d ", record.Priority, record.Weight))
+	if err != nil {
+		return nil, err
 	}
 
-	dataBuilder.WriteString(record.Value)
+	rr, err := convert.FromLibdns(record, p.convertOptions())
+
+	if err != nil {
+		return nil, err
+	}
 
 	parameters := url.Values{}
-	parameters.Set("name", record.Name)
-	parameters.Set("type", record.Type)
-	parameters.Set("data", dataBuilder.String())
+	parameters.Set("name", rr.Name)
+	parameters.Set("type", rr.Type)
+	parameters.Set("data", rr.Data)
 
-	ttl := record.TTL
+	err = p.checkStrictTTL(ctx, record)
 
- 	if ttl < minimumTTL {
-		ttl = minimumTTL
+	if err != nil {
+		return nil, err
 	}
 
-	parameters.Set("ttl", fmt.Sprintf("%d", int(ttl.Seconds())))
+	// Without a TTL, NFSN applies the zone's default
+	if ttl := p.ttlForNfsn(ctx, record.TTL); ttl > 0 {
+		parameters.Set("ttl", fmt.Sprintf("%d", int(ttl.Seconds())))
+	}
 
-	return parameters
+	return parameters, nil
 }
 
 // Constructs a value to pass into an X-NFSN-Authentication header.
@@ -143,83 +359,232 @@ func toNfsnRecordParameters(record libdns.Record) url.Values {
 //
 // Takes `timestamp` and `salt` values for testing.
 func (p *Provider) innerGetAuthValue(req *http.Request, timestamp time.Time, salt string) (string, error) {
-	var bodyBytes []byte
-	var err error
+	authVal, _, err := p.authValueWithKey(req, timestamp, salt)
+	return authVal, err
+}
 
-	if req.Body != nil {
-		bodyBytes, err = io.ReadAll(req.Body)
+// See `innerGetAuthValue`. Also returns the API key the value was computed with, so that it can be
+// masked in errors about the request, or "" if it was a key set with `SetAPIKeyBytes`.
+func (p *Provider) authValueWithKey(req *http.Request, timestamp time.Time, salt string) (string, string, error) {
+	bodyHash, err := hashBody(req)
 
-		if err != nil {
-			return "", err
-		}
+	if err != nil {
+		return "", "", err
 	}
 
-	// Restore the body so it can be read again later
-	req.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-	bodyHash := sha1.Sum(bodyBytes)
-
 	// Build the text to hash
-	hText := fmt.Sprintf("%s;%d;%s;%s;%s;%x", p.Login, timestamp.Unix(), salt, p.APIKey, req.URL.Path, bodyHash)
-	hHash := sha1.Sum([]byte(hText))
+	login, apiKey, err := p.credentials(req.Context())
+
+	if err != nil {
+		return "", "", err
+	}
+
+	// The text is written to the hash in parts so that a key set with `SetAPIKeyBytes` is never
+	// copied
+	hash := sha1.New()
+	fmt.Fprintf(hash, "%s;%d;%s;", login, timestamp.Unix(), salt)
+
+	if apiKey != "" {
+		io.WriteString(hash, apiKey)
+	} else {
+		p.writeAPIKeyBytes(hash)
+	}
+
+	fmt.Fprintf(hash, ";%s;%x", req.URL.Path, bodyHash)
+	hHash := hash.Sum(nil)
 
 	// Format the auth value to send on the wire
-	authVal := fmt.Sprintf("%s;%d;%s;%x", p.Login, timestamp.Unix(), salt, hHash)
-	return authVal, nil
+	authVal := fmt.Sprintf("%s;%d;%s;%x", login, timestamp.Unix(), salt, hHash)
+	return authVal, apiKey, nil
 }
 
-// Generate a random salt usable for generating an X-NFSN-Authentication header value. See
-// `innerGetAuthValue` for details.
-func genSalt() (string, error) {
-	bytes := make([]byte, saltLen)
-	readLen, err := rand.Read(bytes)
+// Returns the SHA1 hash of the body of `req`, leaving the body readable from the start. Where
+// possible the body is hashed as it's streamed rather than held in memory: from a fresh copy if the
+// request has `GetBody` (as requests with in-memory bodies do), or by rewinding it if it's seekable.
+// Other bodies are buffered.
+func hashBody(req *http.Request) ([]byte, error) {
+	hash := sha1.New()
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return hash.Sum(nil), nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer body.Close()
+		_, err = io.Copy(hash, body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return hash.Sum(nil), nil
+	}
+
+	if seeker, ok := req.Body.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.Copy(hash, seeker)
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = seeker.Seek(start, io.SeekStart)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return hash.Sum(nil), nil
+	}
+
+	var buf bytes.Buffer
+	_, err := io.Copy(hash, io.TeeReader(req.Body, &buf))
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if readLen != saltLen {
-		return "", fmt.Errorf("Failed to read enough random bytes")
+	req.Body.Close()
+	req.Body = io.NopCloser(&buf)
+
+	return hash.Sum(nil), nil
+}
+
+// GenerateSalt returns a random alphanumeric string of `length` characters, drawn uniformly from
+// crypto/rand, usable as the salt of an X-NFSN-Authentication header (which NFSN requires to be 16
+// characters). See `innerGetAuthValue` for details.
+func GenerateSalt(length int) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("Invalid salt length %d", length)
 	}
 
 	var sb strings.Builder
+	random := make([]byte, length)
+
+	for sb.Len() < length {
+		_, err := io.ReadFull(rand.Reader, random)
+
+		if err != nil {
+			return "", fmt.Errorf("Failed to read random bytes: %w", err)
+		}
 
-	for b := range bytes {
-		sb.WriteByte(saltChars[b%len(saltChars)])
+		for _, b := range random {
+			if int(b) < saltByteLimit && sb.Len() < length {
+				sb.WriteByte(saltChars[int(b)%len(saltChars)])
+			}
+		}
 	}
 
 	return sb.String(), nil
 }
 
-func uriForZone(zone string, resource string) string {
-	return fmt.Sprintf("%s/dns/%s/%s", apiBase, strings.TrimRight(zone, "."), resource)
+func (p *Provider) apiBase() string {
+	if p.baseURL != "" {
+		return p.baseURL
+	}
+
+	return apiBase
+}
+
+func (p *Provider) uriForZone(zone string, resource string) string {
+	return fmt.Sprintf("%s/dns/%s/%s", p.apiBase(), convert.ASCIIName(strings.TrimRight(zone, ".")), resource)
+}
+
+func (p *Provider) uriForMember(login string, resource string) string {
+	return fmt.Sprintf("%s/member/%s/%s", p.apiBase(), login, resource)
 }
 
-// See `innerGetAuthValue` for details.
-func (p *Provider) getAuthValue(req *http.Request) (string, error) {
-	salt, err := genSalt()
+// See `innerGetAuthValue` for details. Also returns the API key the value was computed with, or ""
+// if it was a key set with `SetAPIKeyBytes`.
+func (p *Provider) getAuthValue(req *http.Request) (string, string, error) {
+	timestamp, salt, err := p.reserveSalt()
 
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return p.innerGetAuthValue(req, time.Now(), salt)
+	return p.authValueWithKey(req, timestamp, salt)
 }
 
-func (p *Provider) ensureClient() {
+func (p *Provider) userAgent() string {
+	userAgent := defaultUserAgent
+
+	if p.UserAgent != "" {
+		userAgent = p.UserAgent
+	}
+
+	if p.UserAgentSuffix == "" {
+		return userAgent
+	}
+
+	return userAgent + " " + p.UserAgentSuffix
+}
+
+// Returns the client to make requests with: HTTPClient if it's set, otherwise a default client
+// that is created on first use.
+func (p *Provider) ensureClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+
+	p.clientMtx.Lock()
+	defer p.clientMtx.Unlock()
+
 	if p.client == nil {
-		p.clientMtx.Lock()
-		defer p.clientMtx.Unlock()
+		timeout := defaultTimeout
 
-		if p.client == nil {
-			p.client = &http.Client{}
+		if p.timeout > 0 {
+			timeout = p.timeout
 		}
+
+		// A transport of its own, rather than the shared default, so that Close only affects this
+		// Provider's connections
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		p.client = &http.Client{Timeout: timeout, Transport: transport}
 	}
+
+	return p.client
+}
+
+// Close closes any idle connections held by the Provider's default HTTP client. It doesn't affect a
+// client set in HTTPClient. The Provider can still be used afterwards, opening new connections as
+// needed. Always returns nil; the error is for compatibility with `io.Closer`.
+func (p *Provider) Close() error {
+	p.clientMtx.Lock()
+	defer p.clientMtx.Unlock()
+
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+		p.client = nil
+	}
+
+	return nil
 }
 
 // Makes a request with the given parameters (see `http.NewRequestWithContext`), adding necessary
-// auth information before executing it.
-func (p *Provider) makeRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
-	p.ensureClient()
+// auth information before executing it. The response body is read in full and restored so that it
+// can be read again by the caller. Unlike `makeRequest`, non-success status codes are not treated as
+// errors.
+func (p *Provider) sendRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
+	client := p.ensureClient()
+
+	if p.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.RequestTimeout)
+		defer cancel()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 
 	if err != nil {
@@ -230,107 +595,624 @@ func (p *Provider) makeRequest(ctx context.Context, method string, url string, b
 		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	authValue, err := p.getAuthValue(req)
+	signingKey, err := p.sign(req)
 
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add(authHeader, authValue)
+	// Errors about the request are redacted with the key it was signed with (see `sentSecrets`)
+	req = req.WithContext(context.WithValue(req.Context(), signingKeyKey{}, signingKey))
+	req.Header.Set("User-Agent", p.userAgent())
+
+	if p.RequestInterceptor != nil {
+		err = p.RequestInterceptor(req)
 
-	resp, err := p.client.Do(req)
+		if err != nil {
+			return nil, p.redactError(err, requestSecrets(req)...)
+		}
+	}
+
+	resp, err := client.Do(req)
 
 	if err != nil {
-		return nil, err
+		return nil, p.redactError(err, requestSecrets(req)...)
 	}
 
 	var bodyBytes []byte
 
 	if resp.Body != nil {
 		bodyBytes, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	p.observeDate(resp)
+
+	return resp, nil
+}
+
+// Makes a request with the given parameters (see `sendRequest`), returning an error wrapping an
+// *APIError if the API responds with a non-success status code. Failed requests are retried as
+// `RetryPolicy` decides, by default with exponential backoff, honoring any Retry-After header, up to
+// `MaxAttempts` times in total. Retries are subject to any retry budget attached to `ctx` (see
+// `withRetryBudget`). A request rejected because its timestamp was too far from NFSN's clock is
+// retried once, immediately, with the clock skew reported by NFSN; if the retry is rejected too the
+// error wraps ErrTimestampRejected.
+func (p *Provider) makeRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
+	var requestBytes []byte
+
+	if body != nil {
+		var err error
+		requestBytes, err = io.ReadAll(body)
+
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API returned non-success status code %s with response body %s. Original error: %w", resp.Status, string(bodyBytes), err)
+	// Reads already in flight may not see this write, so later reads mustn't share them
+	if method != http.MethodGet && !strings.HasSuffix(url, "/listRRs") {
+		p.noteWrite()
+		defer p.noteWrite()
 	}
 
-	return resp, err
+	budget := retryBudgetFromContext(ctx)
+	reloadedKey := false
+	resynced := false
+
+	for attempt := 1; ; attempt++ {
+		var attemptBody io.Reader
+
+		if body != nil {
+			attemptBody = bytes.NewReader(requestBytes)
+		}
+
+		probe, err := p.checkCircuit()
+
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := p.sendRequest(ctx, method, url, attemptBody)
+
+		if err != nil {
+			// The caller giving up says nothing about the API, either way
+			if ctx.Err() == nil {
+				p.recordCircuitOutcome(probe, true)
+			} else if probe {
+				p.abandonCircuitProbe()
+			}
+
+			return nil, err
+		}
+
+		p.recordCircuitOutcome(probe, isRetryable(resp.StatusCode))
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		err = p.apiError(resp, bodyBytes)
+
+		// The key file may have been rotated since it was read
+		if resp.StatusCode == http.StatusUnauthorized && !reloadedKey && p.forgetAPIKeyFile() {
+			reloadedKey = true
+			p.logf("Re-reading API key file after %s %s was rejected", method, url)
+			continue
+		}
+
+		// The clock skew has been updated from the rejection's Date header, and the retry is signed
+		// with a fresh timestamp and salt
+		if isTimestampError(resp, bodyBytes) {
+			if resynced {
+				return nil, &sentinelError{sentinel: ErrTimestampRejected, err: err}
+			}
+
+			resynced = true
+			p.logf("Retrying %s %s after its timestamp was rejected", method, url)
+			continue
+		}
+
+		if zoneErr := p.zoneNotFoundError(ctx, url, resp, err); zoneErr != nil {
+			return nil, zoneErr
+		}
+
+		retry, wait := p.retryPolicy().Retry(resp, attempt)
+
+		if !retry {
+			return nil, rateLimitError(resp, err)
+		}
+
+		// Waiting only to be cancelled would hold up the caller for nothing. Checked first so that a
+		// retry that isn't made doesn't use up the budget.
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return nil, fmt.Errorf("Not retrying, since waiting %s would pass the deadline: %w", wait, rateLimitError(resp, err))
+		}
+
+		if budget != nil && !budget.take() {
+			return nil, &sentinelError{sentinel: ErrRetryBudgetExhausted, err: err}
+		}
+
+		p.logf("Retrying %s %s in %s after attempt %d failed with status %s", method, url, wait, attempt, resp.Status)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
 }
 
 // Execute the given `verb` for each record in `records`. Accumulate successfully process records
-// and return them at the end. If only some records are processed, returns those that were
-// successfull _and_ an error.
+// and return them at the end. Every record is converted up front, so if any record is invalid no
+// requests are made. Records are sent in (name, type) sets (see `groupRecords`), several names at a
+// time (see `MaxConcurrentRequests`). If only some records are processed, e.g. due to a network
+// error, returns those that were successfull _and_ a *BatchError.
 func (p *Provider) processRecords(ctx context.Context, zone string, verb string, records []libdns.Record) ([]libdns.Record, error) {
-	uri := uriForZone(zone, verb)
-	var successfulRecords []libdns.Record
+	if verb != "removeRR" {
+		ctx = p.withZoneMinTTL(ctx, zone)
+	}
 
-	for _, record := range records {
-		params := toNfsnRecordParameters(record)
-		_, err := p.makeRequest(ctx, "POST", uri, strings.NewReader(params.Encode()))
+	ops, err := p.recordOperations(ctx, verb, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	processed, err := p.applyOperations(ctx, zone, ops)
+	return processed, orderBatchError(err, records)
+}
+
+// ValidateRecords checks every one of `records` the way AppendRecords and SetRecords do for `zone`
+// before making any changes. Returns an error for each invalid record, identifying it by its
+// position in `records`, or nil if they're all valid.
+//
+// No changes are made. If `StrictTTL` is set, TTLs are checked against the zone's own minimum, as
+// they would be when writing, so its `minTTL` property may be read (see `MinTTL`); otherwise no
+// requests are made.
+func (p *Provider) ValidateRecords(ctx context.Context, zone string, records []libdns.Record) []error {
+	if p.StrictTTL {
+		ctx = p.withZoneMinTTL(ctx, zone)
+	}
+
+	var errs []error
+
+	for i, record := range records {
+		name, err := relativeName(record.Name, zone)
+
+		if err == nil {
+			record.Name = name
+			_, err = p.toNfsnRecordParameters(ctx, record)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Record %d of %d (%s %q) is invalid: %w", i+1, len(records), record.Type, record.Name, err))
+		}
+	}
+
+	return errs
+}
+
+// Converts every one of `records` to NFSN parameters, so that invalid records are caught before any
+// of them are written. The error identifies the first invalid record.
+func (p *Provider) validateRecords(ctx context.Context, records []libdns.Record) ([]url.Values, error) {
+	allParams := make([]url.Values, 0, len(records))
+
+	for i, record := range records {
+		params, err := p.toNfsnRecordParameters(ctx, record)
 
 		if err != nil {
-			return successfulRecords, err
+			return nil, fmt.Errorf("Record %d of %d (%s %q) is invalid, no changes were made: %w", i+1, len(records), record.Type, record.Name, err)
 		}
 
-		successfulRecords = append(successfulRecords, record)
+		allParams = append(allParams, params)
 	}
 
-	return successfulRecords, nil
+	return allParams, nil
 }
 
-// GetRecords lists all the records in the zone.
-func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
-	resp, err := p.makeRequest(ctx, "POST", uriForZone(zone, "listRRs"), nil)
+// Replaces the records for each (name, type) pair in `records`. `replaceRR` replaces every record
+// for the pair with a single record, so it's sent for the first record of each pair and the rest
+// are added with `addRR`. If only some records are processed, returns those that were successful
+// _and_ a *BatchError.
+func (p *Provider) replaceGroups(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	ctx = p.withZoneMinTTL(ctx, zone)
+	ops, err := p.replaceOperations(ctx, records, false)
 
 	if err != nil {
 		return nil, err
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	replaced, err := p.applyOperations(ctx, zone, ops)
+	return replaced, orderBatchError(err, records)
+}
+
+// GetRecords lists all the records in the zone. Records that NFSN returns in a form that can't be
+// converted to a libdns.Record are skipped; in that case the records that could be converted are
+// returned along with a `RecordParseErrors` describing each record that was skipped.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	return p.GetRecordsFiltered(ctx, zone, RecordFilter{})
+}
+
+// RecordFilter selects records in GetRecordsFiltered. Empty fields match anything.
+type RecordFilter struct {
+	// The record name. Use "@" (or the zone name) for records at the apex of the zone; those are
+	// filtered after the other records matching the filter are fetched.
+	Name string
+
+	// The record type, e.g. "TXT".
+	Type string
+
+	// The record data exactly as NFSN stores it, which for some types differs from the value of
+	// the libdns.Record (see `convert.FromLibdns`), e.g. MX data includes the priority.
+	Data string
+}
+
+// GetRecordsFiltered lists the records in the zone that match `filter`. NFSN does the filtering, so
+// only matching records are transferred and converted, which matters for large zones. Unparseable
+// records are handled as in GetRecords.
+//
+// Names are relative to the zone unless `AbsoluteNames` is set.
+func (p *Provider) GetRecordsFiltered(ctx context.Context, zone string, filter RecordFilter) ([]libdns.Record, error) {
+	if strings.HasSuffix(filter.Name, ".") {
+		name, err := relativeName(filter.Name, zone)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "" {
+			name = "@"
+		}
+
+		filter.Name = name
+	}
+
+	records, err := p.filterRecords(ctx, zone, filter)
+
+	if p.AbsoluteNames {
+		for i := range records {
+			records[i].Name = libdns.AbsoluteName(records[i].Name, zone)
+		}
+	}
+
+	return records, err
+}
+
+// Lists the records in the zone with the given name and type, as GetRecordsFiltered does but always
+// with relative names.
+func (p *Provider) getRecords(ctx context.Context, zone string, name string, recordType string) ([]libdns.Record, error) {
+	return p.filterRecords(ctx, zone, RecordFilter{Name: name, Type: recordType})
+}
+
+// Returns the relative name `name` as a RecordFilter name, in which the apex is "@" since an empty
+// name matches every name.
+func filterName(name string) string {
+	if name == "" {
+		return "@"
+	}
+
+	return name
+}
+
+// Lists the records in the zone that match `filter`, with relative names. A name of "@" selects the
+// apex. NFSN stores apex records with an empty name, which `listRRs` takes to mean any name, so the
+// apex is the one name that can't be filtered on server-side; it's filtered here instead.
+func (p *Provider) filterRecords(ctx context.Context, zone string, filter RecordFilter) ([]libdns.Record, error) {
+	params := url.Values{}
+	apex := filter.Name == "@"
+
+	if filter.Name != "" && !apex {
+		params.Set("name", convert.ASCIIName(filter.Name))
+	}
+
+	if filter.Type != "" {
+		params.Set("type", filter.Type)
+	}
+
+	if filter.Data != "" {
+		params.Set("data", filter.Data)
+	}
+
+	nRecords, err := p.listRecords(ctx, zone, params)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if apex {
+		apexRecords := make([]nfsnRecord, 0, len(nRecords))
+
+		for _, nRecord := range nRecords {
+			if nRecord.Name == "" {
+				apexRecords = append(apexRecords, nRecord)
+			}
+		}
+
+		nRecords = apexRecords
+	}
+
+	return p.toLibdnsRecords(nRecords)
+}
+
+// A page of `listRRs` results. NFSN currently returns every record as a bare JSON array, but if it
+// ever paginates large zones the response is expected to be an object with the records and a
+// token to pass back to fetch the next page.
+type nfsnRecordPage struct {
+	Records []nfsnRecord `json:"records"`
+	Next    string       `json:"next,omitempty"`
+}
+
+// Parses a `listRRs` response body, which may be either a bare array of records or a page. An empty
+// zone may be returned as an empty body, `[]`, `{}`, or `null`, all of which parse as an empty page.
+func parseRecordPage(bodyBytes []byte) (nfsnRecordPage, error) {
+	var page nfsnRecordPage
+	trimmed := bytes.TrimSpace(bodyBytes)
+
+	if len(trimmed) == 0 {
+		return page, nil
+	}
+
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &page.Records)
+		return page, err
+	}
+
+	err := json.Unmarshal(trimmed, &page)
+	return page, err
+}
+
+// Fetches the records in the zone as returned by the API, following pagination tokens until every
+// page has been read. `filter` holds any `name`, `type`, or `data` parameters to have the API filter
+// by.
+func (p *Provider) fetchRecords(ctx context.Context, zone string, filter url.Values) ([]nfsnRecord, error) {
 	var nRecords []nfsnRecord
-	err = json.Unmarshal(bodyBytes, &nRecords)
+	var body io.Reader
 
-	if err != nil {
-		return nil, err
+	if len(filter) > 0 {
+		body = strings.NewReader(filter.Encode())
 	}
 
-	records := make([]libdns.Record, 0, len(nRecords))
+	seen := make(map[string]bool)
 
-	for _, nRecord := range nRecords {
-		record, err := nRecord.Record()
+	for {
+		resp, err := p.makeRequest(ctx, "POST", p.uriForZone(zone, "listRRs"), body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		bodyBytes, err := io.ReadAll(resp.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		page, err := parseRecordPage(bodyBytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		nRecords = append(nRecords, page.Records...)
+
+		if page.Next == "" {
+			return nRecords, nil
+		}
+
+		err = checkNextPage(seen, page.Next)
 
 		if err != nil {
 			return nil, err
 		}
 
+		params := url.Values{}
+
+		for k, v := range filter {
+			params[k] = v
+		}
+
+		params.Set("next", page.Next)
+		body = strings.NewReader(params.Encode())
+	}
+}
+
+// The most `listRRs` pages read for a single listing. A variable so tests can lower it.
+var maxRecordPages = 10000
+
+// Returns an error if the `next` token of a `listRRs` page was already returned by an earlier page
+// of the listing, or if the listing has gone on for `maxRecordPages`, either of which would mean
+// paging forever. `seen` holds the tokens so far, and `next` is added to it.
+func checkNextPage(seen map[string]bool, next string) error {
+	if seen[next] {
+		return fmt.Errorf("The API returned the page token %q twice", next)
+	}
+
+	if len(seen)+1 >= maxRecordPages {
+		return fmt.Errorf("The API returned more than %d pages of records", maxRecordPages)
+	}
+
+	seen[next] = true
+	return nil
+}
+
+// Converts each of `nRecords` to a libdns.Record. Records that can't be converted are skipped and
+// reported in a `RecordParseErrors` returned alongside the converted records. Names are converted to
+// Unicode if `UnicodeNames` is set.
+func (p *Provider) toLibdnsRecords(nRecords []nfsnRecord) ([]libdns.Record, error) {
+	records := make([]libdns.Record, 0, len(nRecords))
+	var parseErrors RecordParseErrors
+
+	for _, nRecord := range nRecords {
+		record, parseErr := p.toLibdnsRecord(nRecord)
+
+		if parseErr != nil {
+			parseErrors = append(parseErrors, *parseErr)
+			continue
+		}
+
 		records = append(records, record)
 	}
 
+	if len(parseErrors) > 0 {
+		return records, parseErrors
+	}
+
 	return records, nil
 }
 
+// Converts `nRecord` to a libdns.Record, as `toLibdnsRecords` does for each record.
+func (p *Provider) toLibdnsRecord(nRecord nfsnRecord) (libdns.Record, *RecordParseError) {
+	record, err := convert.ToLibdns(nRecord, p.convertOptions())
+
+	if err != nil {
+		return libdns.Record{}, &RecordParseError{
+			Name: nRecord.Name,
+			Type: nRecord.Type,
+			Data: nRecord.Data,
+			TTL:  nRecord.TTL,
+			Aux:  nRecord.Aux,
+			Err:  err,
+		}
+	}
+
+	if p.UnicodeNames {
+		record.Name = convert.UnicodeName(record.Name)
+	}
+
+	return record, nil
+}
+
 // AppendRecords adds records to the zone. It returns the records that were added. In the case where
-// only some records succeed returns both the records that were added and an error.
+// only some records succeed returns both the records that were added and a *BatchError, which
+// reports the outcome for each record.
 func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return p.processRecords(ctx, zone, "addRR", records)
+	records, err := relativeRecords(zone, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// TTLs are checked against the zone's own minimum
+	ctx = p.withZoneMinTTL(ctx, zone)
+	_, err = p.validateRecords(ctx, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.CheckConflicts {
+		err := p.checkConflicts(ctx, zone, records)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	added, err := p.processRecords(p.withContinueOnError(ctx), zone, "addRR", records)
+	return p.verifyWrite(ctx, zone, added, err)
 }
 
 // SetRecords sets the records in the zone, either by updating existing records or creating new
-// ones. It returns the updated records. In the case where only some records succeed returns both
-// the records that were replaced and an error.
+// ones. For each (name, type) pair in `records`, the records passed in replace every existing record
+// with that name and type. It returns the updated records. In the case where only some records
+// succeed returns both the records that were replaced and a *BatchError.
+//
+// If `RollbackOnError` is enabled and a write fails part way through, SetRecords makes a best
+// effort to restore the (name, type) groups it changed to the state they were in before the call,
+// as read at the start of the call, and returns a RollbackError. This is inherently racy: changes
+// made to those groups by anyone else during the call will be overwritten. Records NFSN manages
+// itself (system and read-only records) are never rewritten by a rollback.
+//
+// If the API supports `replaceRRSet` (see `Capabilities`) each (name, type) group is replaced in a
+// single request. Otherwise the group is replaced with `replaceRR` for its first record followed by
+// `addRR` for the rest.
 func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return p.processRecords(ctx, zone, "replaceRR", records)
+	records, err := relativeRecords(zone, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Invalid records are reported before the zone is read, so there's never anything to roll back.
+	// TTLs are checked against the zone's own minimum.
+	ctx = p.withZoneMinTTL(ctx, zone)
+	_, err = p.validateRecords(ctx, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities, err := p.Capabilities(ctx, zone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var prior []libdns.Record
+
+	if p.RollbackOnError {
+		prior, err = p.getRecords(ctx, zone, "", "")
+		var parseErrors RecordParseErrors
+
+		if err != nil && !errors.As(err, &parseErrors) {
+			return nil, err
+		}
+	}
+
+	var replaced []libdns.Record
+
+	if capabilities.ReplaceRRSet {
+		replaced, err = p.replaceRecordSets(ctx, zone, records)
+	} else {
+		replaced, err = p.replaceGroups(ctx, zone, records)
+	}
+
+	if err != nil && p.RollbackOnError {
+		return p.rollback(ctx, zone, records, replaced, prior, err)
+	}
+
+	return p.verifyWrite(ctx, zone, replaced, err)
 }
 
 // DeleteRecords deletes the records from the zone. It returns the records that were deleted. In the
-// case where only some records succeed returns both the records that were deleted and an error.
+// case where only some records succeed returns both the records that were deleted and a
+// *BatchError.
+//
+// NFSN only deletes records that match exactly on name, type, and value. A record with an empty
+// value instead deletes every record in the zone with its name and type, e.g. to clean up all
+// `_acme-challenge` TXT records. Such records are looked up first, and the records actually deleted
+// are returned in their place. Records managed by NFSN (those with the "system" scope) aren't
+// matched this way unless `AllowSystemRecordDeletion` is set.
 func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	return p.processRecords(ctx, zone, "removeRR", records)
+	records, err := relativeRecords(zone, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Records with a value are checked before any records are looked up
+	for i, record := range records {
+		if record.Value == "" {
+			continue
+		}
+
+		_, err = p.toNfsnRecordParameters(withRemoval(ctx), record)
+
+		if err != nil {
+			return nil, fmt.Errorf("Record %d of %d (%s %q) is invalid, no changes were made: %w", i+1, len(records), record.Type, record.Name, err)
+		}
+	}
+
+	resolved, err := p.resolveDeletions(ctx, zone, records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.processRecords(p.withContinueOnError(ctx), zone, "removeRR", resolved)
 }
 
 // Interface guards
@@ -339,4 +1221,6 @@ var (
 	_ libdns.RecordAppender = (*Provider)(nil)
 	_ libdns.RecordSetter   = (*Provider)(nil)
 	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
+	_ io.Closer             = (*Provider)(nil)
 )
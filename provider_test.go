@@ -1,14 +1,61 @@
 package nfsn
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/libdns/libdns"
 )
 
+// Returns a Provider that sends its requests to a test server backed by `handler`.
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	// Answers minimum TTL discovery so that handlers only see the requests under test
+	return newRawTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/minTTL") {
+			w.Write([]byte("180"))
+			return
+		}
+
+		handler(w, r)
+	})
+}
+
+// Like `newTestProvider`, but `handler` sees every request.
+func newRawTestProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &Provider{
+		Login:      "testuser",
+		APIKey:     "p3kxmRKf9dk3l6ls",
+		HTTPClient: server.Client(),
+		baseURL:    server.URL,
+	}
+}
+
+// Converts `record` with `toNfsnRecordParameters`, failing the test on error.
+func mustParameters(t *testing.T, p *Provider, ctx context.Context, record libdns.Record) url.Values {
+	params, err := p.toNfsnRecordParameters(ctx, record)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	return params
+}
+
 func TestGetAuthValue(t *testing.T) {
 	p := Provider{
-		Login: "testuser",
+		Login:  "testuser",
 		APIKey: "p3kxmRKf9dk3l6ls",
 	}
 
@@ -32,3 +79,768 @@ func TestGetAuthValue(t *testing.T) {
 		t.Errorf("Expected '%s' but got '%s'", expected, authVal)
 	}
 }
+
+func TestGenerateSalt(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for i := 0; i < 100; i++ {
+		salt, err := GenerateSalt(saltLen)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if len(salt) != saltLen {
+			t.Errorf("Expected a salt of length %d but got '%s'", saltLen, salt)
+		}
+
+		for _, c := range salt {
+			if !strings.ContainsRune(saltChars, c) {
+				t.Errorf("Unexpected character %q in salt '%s'", c, salt)
+			}
+		}
+
+		if seen[salt] {
+			t.Errorf("Salt '%s' was generated twice", salt)
+		}
+
+		seen[salt] = true
+	}
+
+	if salt, err := GenerateSalt(40); err != nil || len(salt) != 40 {
+		t.Errorf("Expected a salt of length 40 but got '%s', %v", salt, err)
+	}
+
+	if _, err := GenerateSalt(0); err == nil {
+		t.Errorf("Expected an error for a zero length")
+	}
+}
+
+func TestHashBody(t *testing.T) {
+	const body = "name=www&type=A&data=192.0.2.1"
+	expected := "ad225266944e6df6d51ff599d19239da4f17fbfc"
+
+	newRequests := map[string]func() *http.Request{
+		"GetBody": func() *http.Request {
+			req, _ := http.NewRequest("POST", "https://api.nearlyfreespeech.net/dns/example.com/addRR", strings.NewReader(body))
+			return req
+		},
+		"seekable": func() *http.Request {
+			req, _ := http.NewRequest("POST", "https://api.nearlyfreespeech.net/dns/example.com/addRR", nil)
+			req.Body = readSeekCloser{strings.NewReader(body)}
+			return req
+		},
+		"stream": func() *http.Request {
+			req, _ := http.NewRequest("POST", "https://api.nearlyfreespeech.net/dns/example.com/addRR", nil)
+			req.Body = io.NopCloser(strings.NewReader(body))
+			return req
+		},
+	}
+
+	for name, newRequest := range newRequests {
+		req := newRequest()
+		hash, err := hashBody(req)
+
+		if err != nil {
+			t.Fatalf("%s: Unexpected error %v", name, err)
+		}
+
+		if fmt.Sprintf("%x", hash) != expected {
+			t.Errorf("%s: Expected '%s' but got '%x'", name, expected, hash)
+		}
+
+		remaining, _ := io.ReadAll(req.Body)
+
+		if string(remaining) != body {
+			t.Errorf("%s: Expected body '%s' but got '%s'", name, body, remaining)
+		}
+	}
+}
+
+type readSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekCloser) Close() error {
+	return nil
+}
+
+func TestTargetTrailingDot(t *testing.T) {
+	cases := []struct {
+		record   libdns.Record
+		expected string
+	}{
+		{libdns.Record{Type: "CNAME", Name: "www", Value: "example.net"}, "example.net."},
+		{libdns.Record{Type: "CNAME", Name: "www", Value: "example.net."}, "example.net."},
+		{libdns.Record{Type: "CNAME", Name: "www", Value: "web"}, "web"},
+		{libdns.Record{Type: "MX", Name: "", Value: "mail.example.net", Priority: 10}, "10 mail.example.net."},
+		{libdns.Record{Type: "MX", Name: "", Value: "mail.example.net.", Priority: 10}, "10 mail.example.net."},
+		{libdns.Record{Type: "NS", Name: "sub", Value: "ns.example.net"}, "ns.example.net."},
+		{libdns.Record{Type: "NS", Name: "sub", Value: "ns.example.net."}, "ns.example.net."},
+		{libdns.Record{Type: "PTR", Name: "1", Value: "host.example.net"}, "host.example.net."},
+		{libdns.Record{Type: "TXT", Name: "", Value: "v=spf1 include:example.net"}, "v=spf1 include:example.net"},
+	}
+
+	for _, c := range cases {
+		data := mustParameters(t, &Provider{}, context.Background(), c.record).Get("data")
+
+		if data != c.expected {
+			t.Errorf("%s: Expected '%s' but got '%s'", c.record.Type, c.expected, data)
+		}
+	}
+}
+
+func TestGetRecordsWithMalformedRecord(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+			{"name": "_sip._tcp", "type": "SRV", "data": "heavy 5060 sip.example.com.", "ttl": 3600, "scope": "member", "aux": 10},
+			{"name": "", "type": "MX", "data": "mail.example.com.", "ttl": 3600, "scope": "member", "aux": 10}
+		]`))
+	})
+
+	records, err := p.GetRecords(context.Background(), "example.com.")
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records but got %d", len(records))
+	}
+
+	if records[0].Value != "192.0.2.1" || records[1].Value != "mail.example.com." {
+		t.Errorf("Unexpected records %+v", records)
+	}
+
+	var parseErrors RecordParseErrors
+
+	if !errors.As(err, &parseErrors) {
+		t.Fatalf("Expected RecordParseErrors but got %v", err)
+	}
+
+	if len(parseErrors) != 1 {
+		t.Fatalf("Expected 1 parse error but got %d", len(parseErrors))
+	}
+
+	if parseErrors[0].Name != "_sip._tcp" || parseErrors[0].Data != "heavy 5060 sip.example.com." {
+		t.Errorf("Unexpected parse error %+v", parseErrors[0])
+	}
+}
+
+func TestUserAgentSuffix(t *testing.T) {
+	var userAgent string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.Write([]byte("[]"))
+	})
+
+	p.GetRecords(context.Background(), "example.com.")
+
+	if userAgent != defaultUserAgent {
+		t.Errorf("Expected '%s' but got '%s'", defaultUserAgent, userAgent)
+	}
+
+	p.UserAgentSuffix = "caddy/2.7.6"
+	p.GetRecords(context.Background(), "example.com.")
+
+	expected := "libdns-nfsn/" + version + " caddy/2.7.6"
+
+	if userAgent != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, userAgent)
+	}
+
+	p.UserAgent = "my-dns-tool/1.0"
+	p.GetRecords(context.Background(), "example.com.")
+
+	if userAgent != "my-dns-tool/1.0 caddy/2.7.6" {
+		t.Errorf("Expected 'my-dns-tool/1.0 caddy/2.7.6' but got '%s'", userAgent)
+	}
+
+	p.UserAgentSuffix = ""
+	p.GetRecords(context.Background(), "example.com.")
+
+	if userAgent != "my-dns-tool/1.0" {
+		t.Errorf("Expected 'my-dns-tool/1.0' but got '%s'", userAgent)
+	}
+}
+
+func TestProviderFormattingRedactsAPIKey(t *testing.T) {
+	p := &Provider{
+		Login:  "testuser",
+		APIKey: "p3kxmRKf9dk3l6ls",
+	}
+
+	for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+		formatted := fmt.Sprintf(format, p)
+
+		if strings.Contains(formatted, p.APIKey) {
+			t.Errorf("%s: API key found in '%s'", format, formatted)
+		}
+
+		if !strings.Contains(formatted, "testuser") {
+			t.Errorf("%s: Login missing from '%s'", format, formatted)
+		}
+	}
+
+	// Only a *Provider is redacted, so one held by value is printed as is
+	byPointer := fmt.Sprintf("%+v", &struct{ Provider *Provider }{p})
+	byValue := fmt.Sprintf("%+v", &struct{ Provider Provider }{Provider{Login: "testuser", APIKey: "p3kxmRKf9dk3l6ls"}})
+
+	if strings.Contains(byPointer, p.APIKey) {
+		t.Errorf("API key found in '%s'", byPointer)
+	}
+
+	if !strings.Contains(byValue, p.APIKey) {
+		t.Errorf("Expected a Provider value to be printed as is but got '%s'", byValue)
+	}
+}
+
+func TestAAAACanonicalization(t *testing.T) {
+	nonCanonical := "2001:0DB8:0000:0000::0001"
+	expected := "2001:db8::1"
+
+	record, err := nfsnRecord{Name: "www", Type: "AAAA", Data: nonCanonical, TTL: 3600}.Record()
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if record.Value != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, record.Value)
+	}
+
+	written := libdns.Record{Type: "AAAA", Name: "www", Value: nonCanonical}
+
+	if data := mustParameters(t, &Provider{}, context.Background(), written).Get("data"); data != nonCanonical {
+		t.Errorf("Expected '%s' but got '%s'", nonCanonical, data)
+	}
+
+	p := &Provider{CanonicalizeAddresses: true}
+
+	if data := mustParameters(t, p, context.Background(), written).Get("data"); data != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, data)
+	}
+}
+
+func TestRequestInterceptor(t *testing.T) {
+	var traceID string
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		traceID = r.Header.Get("X-Trace-Id")
+		w.Write([]byte("[]"))
+	})
+
+	p.RequestInterceptor = func(req *http.Request) error {
+		if req.Header.Get(authHeader) == "" {
+			t.Errorf("Expected auth header to be set before the interceptor runs")
+		}
+
+		req.Header.Set("X-Trace-Id", "abc123")
+		return nil
+	}
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if traceID != "abc123" {
+		t.Errorf("Expected 'abc123' but got '%s'", traceID)
+	}
+
+	interceptorErr := errors.New("interceptor failed")
+	p.RequestInterceptor = func(req *http.Request) error {
+		return interceptorErr
+	}
+
+	_, err = p.GetRecords(context.Background(), "example.com.")
+
+	if !errors.Is(err, interceptorErr) {
+		t.Errorf("Expected interceptor error but got %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request but got %d", requests)
+	}
+}
+
+func TestGetRecordsFollowsPagination(t *testing.T) {
+	var tokens []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		token := r.PostForm.Get("next")
+		tokens = append(tokens, token)
+
+		if token == "" {
+			w.Write([]byte(`{"records": [{"name": "a", "type": "A", "data": "192.0.2.1", "ttl": 3600}], "next": "page2"}`))
+		} else {
+			w.Write([]byte(`{"records": [{"name": "b", "type": "A", "data": "192.0.2.2", "ttl": 3600}]}`))
+		}
+	})
+
+	records, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(records) != 2 || records[0].Name != "a" || records[1].Name != "b" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+
+	expected := []string{"", "page2"}
+
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected %v but got %v", expected, tokens)
+	}
+}
+
+func TestGetRecordsStopsEndlessPagination(t *testing.T) {
+	original := maxRecordPages
+	t.Cleanup(func() { maxRecordPages = original })
+	maxRecordPages = 5
+	requests := 0
+
+	// A token that repeats, then tokens that never end
+	for _, next := range []func() string{
+		func() string { return "again" },
+		func() string { return fmt.Sprintf("page%d", requests+1) },
+	} {
+		requests = 0
+
+		p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			fmt.Fprintf(w, `{"records": [], "next": %q}`, next())
+		})
+
+		_, err := p.GetRecords(context.Background(), "example.com.")
+
+		if err == nil || requests > maxRecordPages {
+			t.Errorf("Expected an error within %d requests but got %v after %d", maxRecordPages, err, requests)
+		}
+	}
+}
+
+func TestRecordFromNFSN(t *testing.T) {
+	cases := []nfsnRecord{
+		{Name: "www", Type: "A", Data: "192.0.2.1", TTL: 3600},
+		{Name: "www", Type: "AAAA", Data: "2001:db8::1", TTL: 3600},
+		{Name: "www", Type: "CNAME", Data: "example.net.", TTL: 3600},
+		{Name: "", Type: "MX", Data: "mail.example.com.", TTL: 3600, Aux: 10},
+		{Name: "sub", Type: "NS", Data: "ns.example.net.", TTL: 3600},
+		{Name: "_sip._tcp", Type: "SRV", Data: "5 5060 sip.example.com.", TTL: 3600, Aux: 10},
+		{Name: "", Type: "TXT", Data: "v=spf1 -all", TTL: 3600},
+		{Name: "_ftp._tcp", Type: "URI", Data: "1 ftp://ftp.example.com/", TTL: 3600, Aux: 10},
+	}
+
+	for _, c := range cases {
+		expected, expectedErr := c.Record()
+		record, err := RecordFromNFSN(c.Name, c.Type, c.Data, c.TTL, c.Aux)
+
+		if err != expectedErr {
+			t.Errorf("%s: Expected error %v but got %v", c.Type, expectedErr, err)
+		}
+
+		if record != expected {
+			t.Errorf("%s: Expected %+v but got %+v", c.Type, expected, record)
+		}
+	}
+
+	record, err := RecordFromNFSN("_sip._tcp", "SRV", "5 5060 sip.example.com.", 3600, 10)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if record.Priority != 10 || record.Weight != 5 || record.Value != "5060 sip.example.com." {
+		t.Errorf("Unexpected SRV record %+v", record)
+	}
+}
+
+func TestGetRecordsEmptyZone(t *testing.T) {
+	for _, body := range []string{"[]", "{}", "", " \n", "null"} {
+		p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+
+		records, err := p.GetRecords(context.Background(), "example.com.")
+
+		if err != nil {
+			t.Errorf("%q: Unexpected error %v", body, err)
+		}
+
+		if records == nil || len(records) != 0 {
+			t.Errorf("%q: Expected an empty, non-nil slice but got %#v", body, records)
+		}
+	}
+}
+
+func TestHTTPSRecord(t *testing.T) {
+	cases := []nfsnRecord{
+		{Name: "", Type: "HTTPS", Data: `1 . alpn="h2,h3"`, TTL: 3600},
+		{Name: "", Type: "HTTPS", Data: `. alpn="h2,h3"`, TTL: 3600, Aux: 1},
+		{Name: "_8443._foo", Type: "SVCB", Data: `1 svc.example.net. port=8443`, TTL: 3600},
+	}
+
+	for _, c := range cases {
+		record, err := c.Record()
+
+		if err != nil {
+			t.Fatalf("%s: Unexpected error %v", c.Data, err)
+		}
+
+		if record.Type != c.Type || record.Priority != 1 {
+			t.Errorf("%s: Unexpected record %+v", c.Data, record)
+		}
+	}
+
+	record, _ := cases[0].Record()
+
+	if record.Value != `. alpn="h2,h3"` {
+		t.Errorf("Expected '%s' but got '%s'", `. alpn="h2,h3"`, record.Value)
+	}
+
+	_, err := nfsnRecord{Name: "", Type: "HTTPS", Data: `. alpn="h2"`, TTL: 3600}.Record()
+
+	if err == nil {
+		t.Errorf("Expected an error for an HTTPS record without a priority")
+	}
+}
+
+func TestHTTPSParameters(t *testing.T) {
+	record := libdns.Record{Type: "HTTPS", Name: "", Value: `. alpn="h2,h3"`, Priority: 1, TTL: time.Hour}
+	params := mustParameters(t, &Provider{}, context.Background(), record)
+	expected := `1 . alpn="h2,h3"`
+
+	if params.Get("type") != "HTTPS" || params.Get("data") != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, params.Get("data"))
+	}
+
+	// Round trip back through the NFSN representation
+	roundTrip, err := nfsnRecord{Name: params.Get("name"), Type: params.Get("type"), Data: params.Get("data"), TTL: 3600}.Record()
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if !SameRecord(roundTrip, record) {
+		t.Errorf("Expected %+v but got %+v", record, roundTrip)
+	}
+}
+
+// Counts the requests made through it
+type countingTransport struct {
+	requests  int
+	transport http.RoundTripper
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return c.transport.RoundTrip(req)
+}
+
+func TestHTTPClient(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+
+	transport := &countingTransport{transport: p.HTTPClient.Transport}
+	p.HTTPClient = &http.Client{Transport: transport}
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if transport.requests != 1 {
+		t.Errorf("Expected 1 request through the supplied client but got %d", transport.requests)
+	}
+
+	if p.client != nil {
+		t.Errorf("Expected no default client to be created")
+	}
+
+	if client := (&Provider{}).ensureClient(); client.Timeout != defaultTimeout {
+		t.Errorf("Expected default timeout %s but got %s", defaultTimeout, client.Timeout)
+	}
+}
+
+func TestGetRecordsFiltered(t *testing.T) {
+	var body string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, _ := io.ReadAll(r.Body)
+		body = string(bodyBytes)
+		w.Write([]byte(`[{"name": "_acme-challenge", "type": "TXT", "data": "token", "ttl": 180}]`))
+	})
+
+	records, err := p.GetRecordsFiltered(context.Background(), "example.com.", RecordFilter{Name: "_acme-challenge", Type: "TXT"})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := "name=_acme-challenge&type=TXT"
+
+	if body != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, body)
+	}
+
+	if len(records) != 1 || records[0].Value != "token" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+
+	_, err = p.GetRecordsFiltered(context.Background(), "example.com.", RecordFilter{Type: "TXT"})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if body != "type=TXT" {
+		t.Errorf("Expected 'type=TXT' but got '%s'", body)
+	}
+
+	_, err = p.GetRecordsFiltered(context.Background(), "example.com.", RecordFilter{Name: "www.example.com.", Data: "192.0.2.1"})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if body != "data=192.0.2.1&name=www" {
+		t.Errorf("Expected 'data=192.0.2.1&name=www' but got '%s'", body)
+	}
+
+	_, err = p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if body != "" {
+		t.Errorf("Expected an empty body but got '%s'", body)
+	}
+
+	// NFSN would take an empty name to mean any name, so the apex is filtered locally
+	for _, name := range []string{"@", "example.com."} {
+		records, err = p.GetRecordsFiltered(context.Background(), "example.com.", RecordFilter{Name: name, Type: "TXT"})
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if body != "type=TXT" || len(records) != 0 {
+			t.Errorf("Expected 'type=TXT' and no records but got '%s' and %+v", body, records)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	p.RequestTimeout = 50 * time.Millisecond
+	p.MaxAttempts = 1
+
+	start := time.Now()
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded but got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the request to time out promptly but it took %s", elapsed)
+	}
+}
+
+func TestALIASParameters(t *testing.T) {
+	for _, rType := range []string{"ALIAS", "ANAME"} {
+		record := libdns.Record{Type: rType, Name: "", Value: "example.herokudns.com", TTL: time.Hour}
+		params := mustParameters(t, &Provider{}, context.Background(), record)
+
+		if params.Get("type") != "ALIAS" || params.Get("data") != "example.herokudns.com." {
+			t.Errorf("%s: Unexpected parameters %v", rType, params)
+		}
+	}
+
+	record, err := nfsnRecord{Name: "", Type: "ALIAS", Data: "example.herokudns.com.", TTL: 3600}.Record()
+
+	if err != nil || record.Type != "ALIAS" || record.Value != "example.herokudns.com." {
+		t.Errorf("Unexpected record %+v, %v", record, err)
+	}
+}
+
+func TestValidateRecords(t *testing.T) {
+	p := &Provider{}
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.1"},
+		{Type: "CAA", Name: "", Value: "issue letsencrypt.org"},
+		{Type: "TXT", Name: "", Value: "v=spf1 -all"},
+		{Type: "SSHFP", Name: "host", Value: "4 2 abcd"},
+	}
+
+	errs := p.ValidateRecords(context.Background(), "example.com.", records)
+
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors but got %v", errs)
+	}
+
+	if !strings.Contains(errs[0].Error(), "Record 2 of 4") || !strings.Contains(errs[1].Error(), "Record 4 of 4") {
+		t.Errorf("Expected errors for records 2 and 4 but got %v", errs)
+	}
+
+	if errs := p.ValidateRecords(context.Background(), "example.com.", records[:1]); errs != nil {
+		t.Errorf("Expected no errors but got %v", errs)
+	}
+
+	// Names are taken relative to the zone, as when writing
+	absolute := []libdns.Record{
+		{Type: "A", Name: "www.example.com.", Value: "192.0.2.1"},
+		{Type: "A", Name: "www.example.net.", Value: "192.0.2.1"},
+	}
+
+	if errs := p.ValidateRecords(context.Background(), "example.com.", absolute); len(errs) != 1 || !strings.Contains(errs[0].Error(), "Record 2 of 2") {
+		t.Errorf("Expected an error for the name outside the zone but got %v", errs)
+	}
+}
+
+func TestPassthroughTypes(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "", "type": "CAA", "data": "issue letsencrypt.org", "ttl": 3600, "aux": 128},
+			{"name": "", "type": "OPENPGPKEY", "data": "mQINBFit2jsBEADrbl5vjVxYeAE0g0IDYCBpHirv1Sjlqxx5gjtPhb2YhvyDMXjq", "ttl": 3600}
+		]`))
+	})
+	p.PassthroughTypes = []string{"caa"}
+
+	records, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if records[0].Value != "issue letsencrypt.org" || records[0].Priority != 128 {
+		t.Errorf("Expected the CAA record to be passed through but got %+v", records[0])
+	}
+
+	if records[1].Type != "OPENPGPKEY" || records[1].Value != "mQINBFit2jsBEADrbl5vjVxYeAE0g0IDYCBpHirv1Sjlqxx5gjtPhb2YhvyDMXjq" {
+		t.Errorf("Expected an unknown type to be passed through but got %+v", records[1])
+	}
+
+	record := libdns.Record{Type: "CAA", Name: "", Value: "issue letsencrypt.org"}
+
+	if data := mustParameters(t, p, context.Background(), record).Get("data"); data != "issue letsencrypt.org" {
+		t.Errorf("Expected 'issue letsencrypt.org' but got '%s'", data)
+	}
+}
+
+func TestGetRecordsWithLOC(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600},
+			{"name": "", "type": "LOC", "data": "52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m", "ttl": 3600}
+		]`))
+	})
+
+	records, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(records) != 2 || records[1].Type != "LOC" || records[1].Value != "52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m" {
+		t.Errorf("Unexpected records %+v", records)
+	}
+}
+
+func TestAppendRecordsBatchError(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		if r.PostForm.Get("data") == "b" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	records := []libdns.Record{
+		{Type: "TXT", Name: "www", Value: "a"},
+		{Type: "A", Name: "mail", Value: "192.0.2.1"},
+		{Type: "TXT", Name: "www", Value: "b"},
+		{Type: "TXT", Name: "www", Value: "c"},
+	}
+
+	added, err := p.AppendRecords(context.Background(), "example.com.", records)
+	var batchErr *BatchError
+
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected BatchError but got %v", err)
+	}
+
+	if !reflect.DeepEqual(added, records[:1]) {
+		t.Errorf("Expected %+v but got %+v", records[:1], added)
+	}
+
+	// The www TXT set is sent first, and the batch stops when its second record fails
+	var results []string
+
+	for _, result := range batchErr.Results {
+		results = append(results, fmt.Sprintf("%s %t %t", result.Record.Value, result.Attempted, result.Err != nil))
+	}
+
+	expected := []string{"a true false", "192.0.2.1 false false", "b true true", "c false false"}
+
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %v but got %v", expected, results)
+	}
+
+	if !reflect.DeepEqual(batchErr.Failed(), records[1:]) {
+		t.Errorf("Expected %+v to be failed but got %+v", records[1:], batchErr.Failed())
+	}
+
+	if errors.Unwrap(err) != batchErr.Results[2].Err {
+		t.Errorf("Expected the batch error to wrap the failure but got %v", errors.Unwrap(err))
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	status := http.StatusBadRequest
+	body := `{"error": "Bad Request", "debug": "The data field is not a valid IPv4 address."}`
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+
+	_, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}})
+	var apiErr *APIError
+
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected APIError but got %v", err)
+	}
+
+	expected := APIError{StatusCode: http.StatusBadRequest, Message: "Bad Request", Debug: "The data field is not a valid IPv4 address."}
+
+	if *apiErr != expected {
+		t.Errorf("Expected %+v but got %+v", expected, *apiErr)
+	}
+
+	// The zone's minTTL property can't be read either, so the zone doesn't exist
+	p = newRawTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Not here"))
+	})
+	_, err = p.GetRecords(context.Background(), "example.com.")
+
+	if !errors.Is(err, ErrZoneNotFound) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Body != "Not here" {
+		t.Errorf("Expected ErrZoneNotFound wrapping a 404 APIError but got %v", err)
+	}
+}
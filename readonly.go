@@ -0,0 +1,28 @@
+package nfsn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrReadOnlyRecord is returned for attempts to add, replace, or remove a record of a type that
+// NFSN manages itself, such as the DS and DNSKEY records of a DNSSEC-signed zone.
+var ErrReadOnlyRecord = errors.New("Record type is read-only")
+
+// Record types that are returned by GetRecords but can't be changed through the API
+var readOnlyTypes = map[string]bool{
+	"DNSKEY": true,
+	"DS":     true,
+}
+
+// Returns an error wrapping ErrReadOnlyRecord if `record` can't be changed through the API.
+func checkReadOnly(record libdns.Record) error {
+	if readOnlyTypes[strings.ToUpper(record.Type)] {
+		return fmt.Errorf("%w: %s record %q is managed by NFSN", ErrReadOnlyRecord, record.Type, record.Name)
+	}
+
+	return nil
+}
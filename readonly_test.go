@@ -0,0 +1,52 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestReadOnlyRecords(t *testing.T) {
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[
+			{"name": "", "type": "DNSKEY", "data": "257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==", "ttl": 3600, "scope": "system"},
+			{"name": "sub", "type": "DS", "data": "60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118", "ttl": 3600, "scope": "member"}
+		]`))
+	})
+
+	records, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(records) != 2 || records[1].Type != "DS" || records[1].Value != "60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118" {
+		t.Fatalf("Unexpected records %+v", records)
+	}
+
+	requests = 0
+
+	for _, record := range records {
+		if _, err := p.DeleteRecords(context.Background(), "example.com.", []libdns.Record{record}); !errors.Is(err, ErrReadOnlyRecord) {
+			t.Errorf("%s: Expected ErrReadOnlyRecord but got %v", record.Type, err)
+		}
+
+		if _, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{record}); !errors.Is(err, ErrReadOnlyRecord) {
+			t.Errorf("%s: Expected ErrReadOnlyRecord but got %v", record.Type, err)
+		}
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no requests but %d were made", requests)
+	}
+
+	if errs := p.ValidateRecords(context.Background(), "example.com.", []libdns.Record{{Type: "ds", Name: "sub", Value: "1 2 3 ABCD"}}); len(errs) != 1 || !errors.Is(errs[0], ErrReadOnlyRecord) {
+		t.Errorf("Expected ErrReadOnlyRecord but got %v", errs)
+	}
+}
@@ -0,0 +1,108 @@
+package nfsn
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// Masks all but the first couple characters of a secret.
+func redact(secret string) string {
+	if len(secret) < 8 {
+		return strings.Repeat("*", len(secret))
+	}
+
+	return secret[:2] + strings.Repeat("*", len(secret)-2)
+}
+
+// Returns `s` with every occurrence of the Provider's API keys, and of `extra` secrets, masked. The
+// keys are those configured on the Provider, including one set with SetAPIKeyBytes. Keys from a
+// CredentialSource aren't kept, so errors about a request are masked with the secrets it was sent
+// with instead (see `requestSecrets`).
+func (p *Provider) redactSecrets(s string, extra ...string) string {
+	secrets := append([]string{p.APIKey}, extra...)
+
+	p.fileAPIKeyMtx.Lock()
+	secrets = append(secrets, p.fileAPIKey)
+	p.fileAPIKeyMtx.Unlock()
+
+	p.keyringAPIKeyMtx.Lock()
+	secrets = append(secrets, p.keyringAPIKey)
+	p.keyringAPIKeyMtx.Unlock()
+
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redact(secret))
+		}
+	}
+
+	return p.redactAPIKeyBytes(s)
+}
+
+// Masks the key set with SetAPIKeyBytes in `s`, without copying the key into a string.
+func (p *Provider) redactAPIKeyBytes(s string) string {
+	p.apiKeyBytesMtx.Lock()
+	defer p.apiKeyBytesMtx.Unlock()
+
+	key := p.apiKeyBytes
+
+	if len(key) == 0 || !bytes.Contains([]byte(s), key) {
+		return s
+	}
+
+	mask := bytes.Repeat([]byte("*"), len(key))
+
+	if len(key) >= 8 {
+		copy(mask, key[:2])
+	}
+
+	return string(bytes.ReplaceAll([]byte(s), key, mask))
+}
+
+// An error whose message has had secrets masked. Unwraps to the original error.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+type signingKeyKey struct{}
+
+// Returns the secrets `req` was sent with: its auth header value, and the API key that was signed
+// with, which the request's context carries only for as long as the request is referenced.
+func requestSecrets(req *http.Request) []string {
+	signingKey, _ := req.Context().Value(signingKeyKey{}).(string)
+	return []string{req.Header.Get(authHeader), signingKey}
+}
+
+// Returns the secrets sent with the request that `resp` is the response to (see `requestSecrets`).
+func sentSecrets(resp *http.Response) []string {
+	if resp.Request == nil {
+		return nil
+	}
+
+	return requestSecrets(resp.Request)
+}
+
+// Returns `err` with any secrets in its message masked (see `redactSecrets`).
+func (p *Provider) redactError(err error, extra ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	redacted := p.redactSecrets(msg, extra...)
+
+	if redacted == msg {
+		return err
+	}
+
+	return &redactedError{msg: redacted, err: err}
+}
@@ -0,0 +1,110 @@
+package nfsn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSecretsRedacted(t *testing.T) {
+	setRetryDelay(t, 0)
+	var authValue string
+
+	// A misbehaving server that echoes the secrets back
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		authValue = r.Header.Get(authHeader)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf(`{"error":"%s","debug":"p3kxmRKf9dk3l6ls"}`, authValue)))
+	})
+
+	var logs bytes.Buffer
+	p.logger = log.New(&logs, "", 0)
+	p.RequestInterceptor = func(r *http.Request) error {
+		if r.Method == "GET" {
+			return fmt.Errorf("Refusing to send %s", r.Header.Get(authHeader))
+		}
+
+		return nil
+	}
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	_, interceptErr := p.ListZones(context.Background())
+
+	if interceptErr == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	for _, output := range []string{err.Error(), interceptErr.Error(), logs.String(), fmt.Sprintf("%v %+v %#v", p, p, p)} {
+		if strings.Contains(output, "p3kxmRKf9dk3l6ls") {
+			t.Errorf("Expected the API key to be redacted from '%s'", output)
+		}
+
+		if strings.Contains(output, strings.Split(authValue, ";")[3]) {
+			t.Errorf("Expected the auth header to be redacted from '%s'", output)
+		}
+	}
+
+	p.RequestInterceptor = func(r *http.Request) error {
+		return context.Canceled
+	}
+	_, err = p.ListZones(context.Background())
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected redacted errors to unwrap, but got %v", err)
+	}
+}
+
+// Always hands out the same key
+type fixedCredentials struct {
+	apiKey string
+}
+
+func (f fixedCredentials) Credentials(ctx context.Context) (string, string, error) {
+	return "testuser", f.apiKey, nil
+}
+
+func TestSecretsRedactedFromOtherSources(t *testing.T) {
+	// A misbehaving server that echoes the secrets back
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Bad key q8ZtV3nW0xLk2mRj","debug":"Bad key b5HsN7cY1pQe4uTd"}`))
+	}
+
+	p := newTestProvider(t, handler)
+	p.CredentialSource = fixedCredentials{apiKey: "q8ZtV3nW0xLk2mRj"}
+	_, sourceErr := p.GetRecords(context.Background(), "example.com.")
+
+	p = newTestProvider(t, handler)
+	p.SetAPIKeyBytes([]byte("b5HsN7cY1pQe4uTd"))
+	_, bytesErr := p.GetRecords(context.Background(), "example.com.")
+
+	if sourceErr == nil || bytesErr == nil {
+		t.Fatalf("Expected errors but got %v and %v", sourceErr, bytesErr)
+	}
+
+	if strings.Contains(sourceErr.Error(), "q8ZtV3nW0xLk2mRj") {
+		t.Errorf("Expected the credential source's key to be redacted from '%s'", sourceErr)
+	}
+
+	// The key is masked using the request it was sent with, not kept on the Provider
+	p.CredentialSource = fixedCredentials{apiKey: "q8ZtV3nW0xLk2mRj"}
+	p.GetRecords(context.Background(), "example.com.")
+
+	if p.redactSecrets("q8ZtV3nW0xLk2mRj") != "q8ZtV3nW0xLk2mRj" {
+		t.Errorf("Expected the credential source's key not to be kept after the request")
+	}
+
+	if strings.Contains(bytesErr.Error(), "b5HsN7cY1pQe4uTd") || !strings.Contains(bytesErr.Error(), "b5**************") {
+		t.Errorf("Expected the key set as bytes to be redacted from '%s'", bytesErr)
+	}
+}
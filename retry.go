@@ -0,0 +1,167 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default maximum number of times a single request is attempted
+const defaultMaxAttempts = 3
+
+// Delay before the first retry. Each subsequent retry waits twice as long as the previous one.
+var retryDelay = time.Second
+
+// Longest a Retry-After header may make a retry wait
+const maxRetryAfter = 5 * time.Minute
+
+// ErrRetryBudgetExhausted is returned when a request in a batch fails with a retryable error after
+// the batch's retry budget (see `Provider.BatchRetryBudget`) has been used up.
+var ErrRetryBudgetExhausted = errors.New("Retry budget for batch exhausted")
+
+// ErrRateLimited is returned when NFSN responds 429 Too Many Requests and the request isn't retried,
+// e.g. because every attempt was rate limited or the wait NFSN asks for would pass the caller's
+// deadline.
+var ErrRateLimited = errors.New("Rate limited by NFSN")
+
+// Returns `err`, the error for the failed response `resp`, wrapped in ErrRateLimited if NFSN
+// responded 429 Too Many Requests.
+func rateLimitError(resp *http.Response, err error) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &sentinelError{sentinel: ErrRateLimited, err: err}
+	}
+
+	return err
+}
+
+func isRetryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// RetryPolicy decides whether a request that NFSN responded to with a non-success status is retried.
+// Requests that fail without a response, e.g. due to a network error, aren't retried.
+type RetryPolicy interface {
+	// Retry is called after attempt number `attempt`, counting from 1, failed with `resp`. The
+	// request can be inspected through resp.Request, but its body has already been read. Returns
+	// whether to retry, and if so how long to wait first.
+	Retry(resp *http.Response, attempt int) (bool, time.Duration)
+}
+
+// RetryPolicyFunc adapts an ordinary function to the RetryPolicy interface.
+type RetryPolicyFunc func(resp *http.Response, attempt int) (bool, time.Duration)
+
+// Retry calls f(resp, attempt).
+func (f RetryPolicyFunc) Retry(resp *http.Response, attempt int) (bool, time.Duration) {
+	return f(resp, attempt)
+}
+
+// DefaultRetryPolicy is the retry behavior used unless `Provider.RetryPolicy` is set. It retries
+// 429 Too Many Requests responses, and 5xx responses to requests that only read, waiting as long as
+// a Retry-After header asks (up to 5 minutes) or otherwise backing off exponentially from 1 second,
+// until MaxAttempts attempts have been made. Other policies can delegate to it.
+type DefaultRetryPolicy struct {
+	// Maximum number of attempts. Defaults to 3.
+	MaxAttempts int
+
+	// If true, requests that change records are retried after 5xx responses too. A 5xx response
+	// doesn't say whether the change was made, so retrying e.g. an addRR may add the record twice.
+	RetryWrites bool
+}
+
+// Retry implements RetryPolicy.
+func (d DefaultRetryPolicy) Retry(resp *http.Response, attempt int) (bool, time.Duration) {
+	maxAttempts := d.MaxAttempts
+
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	if !isRetryable(resp.StatusCode) || attempt >= maxAttempts {
+		return false, 0
+	}
+
+	// NFSN rejects rate limited requests before acting on them, so only those are always safe to retry
+	if resp.StatusCode != http.StatusTooManyRequests && !d.RetryWrites && !isReadRequest(resp.Request) {
+		return false, 0
+	}
+
+	return true, retryWait(resp, attempt)
+}
+
+// Returns whether `req` only reads from NFSN, so that repeating it has no effect.
+func isReadRequest(req *http.Request) bool {
+	return req != nil && (req.Method == http.MethodGet || strings.HasSuffix(req.URL.Path, "/listRRs"))
+}
+
+func (p *Provider) retryPolicy() RetryPolicy {
+	if p.RetryPolicy != nil {
+		return p.RetryPolicy
+	}
+
+	return DefaultRetryPolicy{MaxAttempts: p.MaxAttempts}
+}
+
+// Returns how long to wait before retrying after `attempt` failed with `resp`. Uses the response's
+// Retry-After header, in either its seconds or HTTP date form, if it has one; otherwise backs off
+// exponentially.
+func retryWait(resp *http.Response, attempt int) time.Duration {
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		var wait time.Duration
+
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		} else if date, err := http.ParseTime(retryAfter); err == nil {
+			wait = time.Until(date)
+		}
+
+		if wait < 0 {
+			wait = 0
+		} else if wait > maxRetryAfter {
+			wait = maxRetryAfter
+		}
+
+		return wait
+	}
+
+	return retryDelay << (attempt - 1)
+}
+
+// Tracks the retries remaining for all of the requests in a batch
+type retryBudget struct {
+	remaining int
+	mtx       sync.Mutex
+}
+
+// Consumes one retry from the budget. Returns false if none remain.
+func (b *retryBudget) take() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.remaining <= 0 {
+		return false
+	}
+
+	b.remaining--
+	return true
+}
+
+type retryBudgetKey struct{}
+
+// Attaches a fresh retry budget to `ctx` if `BatchRetryBudget` is configured and `ctx` doesn't
+// already carry one. Requests made with the returned context share the budget.
+func (p *Provider) withRetryBudget(ctx context.Context) context.Context {
+	if p.BatchRetryBudget <= 0 || retryBudgetFromContext(ctx) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, retryBudgetKey{}, &retryBudget{remaining: p.BatchRetryBudget})
+}
+
+func retryBudgetFromContext(ctx context.Context) *retryBudget {
+	budget, _ := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	return budget
+}
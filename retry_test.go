@@ -0,0 +1,242 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Sets the delay before the first retry for the rest of the test.
+func setRetryDelay(t *testing.T, delay time.Duration) {
+	original := retryDelay
+	t.Cleanup(func() { retryDelay = original })
+	retryDelay = delay
+}
+
+func TestBatchRetryBudgetExhausted(t *testing.T) {
+	setRetryDelay(t, 0)
+	requests := 0
+
+	// Fail the first attempt for every record
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	p.BatchRetryBudget = 2
+	p.RetryPolicy = DefaultRetryPolicy{RetryWrites: true}
+
+	records := []libdns.Record{
+		{Type: "A", Name: "a", Value: "192.0.2.1"},
+		{Type: "A", Name: "b", Value: "192.0.2.2"},
+		{Type: "A", Name: "c", Value: "192.0.2.3"},
+		{Type: "A", Name: "d", Value: "192.0.2.4"},
+	}
+
+	added, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("Expected ErrRetryBudgetExhausted but got %v", err)
+	}
+
+	if len(added) != 2 {
+		t.Errorf("Expected 2 records to be added but got %d", len(added))
+	}
+
+	if requests != 5 {
+		t.Errorf("Expected 5 requests but got %d", requests)
+	}
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	setRetryDelay(t, 0)
+	var bodies []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		bodies = append(bodies, r.PostForm.Encode())
+
+		switch len(bodies) {
+		case 1:
+			w.WriteHeader(http.StatusInternalServerError)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+	p.RetryPolicy = DefaultRetryPolicy{RetryWrites: true}
+
+	records := []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}}
+	added, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(added) != 1 || len(bodies) != 3 {
+		t.Fatalf("Expected 3 attempts but got %d", len(bodies))
+	}
+
+	// The body must be resent in full on each attempt
+	for _, body := range bodies {
+		if body != bodies[0] || body == "" {
+			t.Errorf("Expected '%s' but got '%s'", bodies[0], body)
+		}
+	}
+
+	bodies = nil
+	p.RetryPolicy = DefaultRetryPolicy{MaxAttempts: 2, RetryWrites: true}
+	_, err = p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err == nil || len(bodies) != 2 {
+		t.Errorf("Expected failure after 2 attempts but made %d with error %v", len(bodies), err)
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	setRetryDelay(t, time.Second)
+
+	resp := &http.Response{Header: http.Header{}}
+
+	if wait := retryWait(resp, 3); wait != 4*time.Second {
+		t.Errorf("Expected 4s but got %s", wait)
+	}
+
+	resp.Header.Set("Retry-After", "7")
+
+	if wait := retryWait(resp, 1); wait != 7*time.Second {
+		t.Errorf("Expected 7s but got %s", wait)
+	}
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+
+	if wait := retryWait(resp, 1); wait != 0 {
+		t.Errorf("Expected 0s but got %s", wait)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	setRetryDelay(t, 0)
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if !errors.Is(err, ErrRateLimited) || requests != defaultMaxAttempts {
+		t.Errorf("Expected ErrRateLimited after %d attempts but got %v after %d", defaultMaxAttempts, err, requests)
+	}
+
+	// A wait past the deadline fails straight away rather than waiting to be cancelled
+	p = newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err = p.GetRecords(ctx, "example.com.")
+
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited but got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected to fail promptly but took %s", elapsed)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	setRetryDelay(t, 0)
+	requests := make(map[string]int)
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	// Retries reads, including listRRs, up to 5 times but never retries writes
+	p.RetryPolicy = RetryPolicyFunc(func(resp *http.Response, attempt int) (bool, time.Duration) {
+		if !strings.HasSuffix(resp.Request.URL.Path, "/listRRs") && resp.Request.Method != http.MethodGet {
+			return false, 0
+		}
+
+		return DefaultRetryPolicy{MaxAttempts: 5}.Retry(resp, attempt)
+	})
+
+	p.GetRecords(context.Background(), "example.com.")
+	p.AppendRecords(context.Background(), "example.com.", []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}})
+
+	if requests["/dns/example.com/listRRs"] != 5 || requests["/dns/example.com/addRR"] != 1 {
+		t.Errorf("Expected 5 listRRs and 1 addRR requests but got %v", requests)
+	}
+}
+
+func TestDefaultRetryPolicySkipsWrites(t *testing.T) {
+	setRetryDelay(t, 0)
+	requests := make(map[string]int)
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+
+		if r.URL.Path == "/dns/example.com/addRR" && requests[r.URL.Path] == 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	records := []libdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1"}}
+	p.GetRecords(context.Background(), "example.com.")
+	p.AppendRecords(context.Background(), "example.com.", records)
+
+	// The addRR may have been applied, so it isn't repeated
+	if requests["/dns/example.com/listRRs"] != defaultMaxAttempts || requests["/dns/example.com/addRR"] != 1 {
+		t.Errorf("Expected %d listRRs and 1 addRR requests but got %v", defaultMaxAttempts, requests)
+	}
+
+	// Rate limited writes were never applied, so they are
+	p.AppendRecords(context.Background(), "example.com.", records)
+
+	if requests["/dns/example.com/addRR"] != 3 {
+		t.Errorf("Expected 3 addRR requests but got %v", requests)
+	}
+}
+
+func TestRetryBudgetKeptPastDeadline(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	p.BatchRetryBudget = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ctx = p.withRetryBudget(ctx)
+	_, err := p.makeRequest(ctx, "POST", p.uriForZone("example.com.", "listRRs"), nil)
+
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited but got %v", err)
+	}
+
+	// The retry wasn't made, so the budget is untouched
+	if remaining := retryBudgetFromContext(ctx).remaining; remaining != 1 {
+		t.Errorf("Expected 1 retry to remain but got %d", remaining)
+	}
+}
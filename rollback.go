@@ -0,0 +1,113 @@
+package nfsn
+
+import (
+	"context"
+
+	"github.com/libdns/libdns"
+)
+
+// Restores the (name, type) groups that a failed SetRecords call may have changed to their state in
+// `prior`. `replaced` are the records that were successfully written before the failure `err`.
+//
+// Groups without records managed by NFSN are replaced with their prior records, or deleted if they
+// didn't exist. Records NFSN manages (system and read-only records) can't be written back, so in
+// groups holding them the records written by the call are removed individually and the prior
+// member records re-added instead.
+//
+// Returns no records if the rollback succeeded, since none of the changes remain; otherwise returns
+// `replaced`. The error is always a RollbackError.
+func (p *Provider) rollback(ctx context.Context, zone string, records []libdns.Record, replaced []libdns.Record, prior []libdns.Record, err error) ([]libdns.Record, error) {
+	affected := make(map[rrSetKey]bool)
+	written := make(map[rrSetKey][]libdns.Record)
+
+	for _, record := range replaced {
+		affected[rrSetKeyFor(record)] = true
+		written[rrSetKeyFor(record)] = append(written[rrSetKeyFor(record)], record)
+	}
+
+	// Without concurrent requests groups are written one at a time, so the first group that wasn't
+	// completely written is the one being written when the failure happened, and may have been
+	// partially changed. Otherwise any group that wasn't completely written may have been.
+	sets := groupRecords(records, nil)
+
+	for _, set := range sets {
+		if len(written[set.key]) < len(set.records) {
+			affected[set.key] = true
+
+			if p.maxConcurrentRequests() == 1 {
+				break
+			}
+		}
+	}
+
+	fixed := make(map[rrSetKey]bool)
+	priorMember := make(map[rrSetKey][]libdns.Record)
+
+	for _, record := range prior {
+		key := rrSetKeyFor(record)
+
+		if IsSystemRecord(record) || checkReadOnly(record) != nil {
+			fixed[key] = true
+		} else {
+			priorMember[key] = append(priorMember[key], record)
+		}
+	}
+
+	var restore, remove, readd []libdns.Record
+
+	for _, set := range sets {
+		key := set.key
+
+		switch {
+		case !affected[key]:
+		case fixed[key]:
+			remove = append(remove, missingRecords(written[key], priorMember[key])...)
+			readd = append(readd, missingRecords(priorMember[key], written[key])...)
+		case len(priorMember[key]) > 0:
+			restore = append(restore, priorMember[key]...)
+		default:
+			// Groups that didn't exist before the call are deleted entirely
+			remove = append(remove, libdns.Record{Name: key.name, Type: key.rType})
+		}
+	}
+
+	_, rollbackErr := p.replaceGroups(ctx, zone, restore)
+
+	if rollbackErr == nil && len(remove) > 0 {
+		_, rollbackErr = p.DeleteRecords(ctx, zone, remove)
+	}
+
+	if rollbackErr == nil && len(readd) > 0 {
+		_, rollbackErr = p.processRecords(ctx, zone, "addRR", readd)
+	}
+
+	if rollbackErr != nil {
+		return replaced, &RollbackError{Err: err, RollbackErr: rollbackErr}
+	}
+
+	return nil, &RollbackError{Err: err}
+}
+
+// Returns the records in `a` that aren't in `b`, comparing them as they would be stored.
+func missingRecords(a []libdns.Record, b []libdns.Record) []libdns.Record {
+	var missing []libdns.Record
+
+	for _, record := range a {
+		found := false
+
+		for _, other := range b {
+			other.TTL = record.TTL
+
+			if Equal(record, other) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, record)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,189 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestSetRecordsRollback(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/replaceRRSet":
+			w.WriteHeader(http.StatusNotFound)
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[
+				{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+				{"name": "www", "type": "A", "data": "192.0.2.2", "ttl": 3600, "scope": "member"}
+			]`))
+		default:
+			mutation := r.URL.Path[len("/dns/example.com/"):] + " " + r.PostForm.Get("name") + " " + r.PostForm.Get("data")
+			mutations = append(mutations, mutation)
+
+			if mutation == "replaceRR mail 192.0.2.20" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+	})
+	p.RollbackOnError = true
+
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.10"},
+		{Type: "A", Name: "mail", Value: "192.0.2.20"},
+	}
+
+	replaced, err := p.SetRecords(context.Background(), "example.com.", records)
+	var rollbackErr *RollbackError
+
+	if !errors.As(err, &rollbackErr) {
+		t.Fatalf("Expected RollbackError but got %v", err)
+	}
+
+	if rollbackErr.RollbackErr != nil {
+		t.Errorf("Unexpected rollback error %v", rollbackErr.RollbackErr)
+	}
+
+	if len(replaced) != 0 {
+		t.Errorf("Expected no records after a successful rollback but got %+v", replaced)
+	}
+
+	expected := []string{
+		"replaceRR www 192.0.2.10",
+		"replaceRR mail 192.0.2.20",
+		"replaceRR www 192.0.2.1",
+		"addRR www 192.0.2.2",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+}
+
+func TestSetRecordsRollbackInvalidRecord(t *testing.T) {
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("[]"))
+	})
+	p.RollbackOnError = true
+
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.10"},
+		{Type: "CAA", Name: "", Value: "bogus"},
+	}
+
+	_, err := p.SetRecords(context.Background(), "example.com.", records)
+	var rollbackErr *RollbackError
+
+	if err == nil || errors.As(err, &rollbackErr) {
+		t.Errorf("Expected a validation error but got %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no requests but %d were made", requests)
+	}
+}
+
+func TestSetRecordsRollbackSkipsSystemRecords(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/replaceRRSet":
+			w.WriteHeader(http.StatusNotFound)
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[
+				{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+				{"name": "", "type": "NS", "data": "ns.example.net.", "ttl": 3600, "scope": "member"}
+			]`))
+		default:
+			mutation := r.URL.Path[len("/dns/example.com/"):] + " " + r.PostForm.Get("name") + " " + r.PostForm.Get("data")
+			mutations = append(mutations, mutation)
+
+			if r.PostForm.Get("data") == "ns2.example.org." {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+	})
+	p.RollbackOnError = true
+
+	records := []libdns.Record{
+		{Type: "NS", Name: "", Value: "ns1.example.org."},
+		{Type: "NS", Name: "", Value: "ns2.example.org."},
+	}
+
+	_, err := p.SetRecords(context.Background(), "example.com.", records)
+	var rollbackErr *RollbackError
+
+	if !errors.As(err, &rollbackErr) || rollbackErr.RollbackErr != nil {
+		t.Fatalf("Expected a successful rollback but got %v", err)
+	}
+
+	// The system record can't be written back, so the written record is removed and the member
+	// record re-added individually rather than replacing the set
+	expected := []string{
+		"replaceRR  ns1.example.org.",
+		"addRR  ns2.example.org.",
+		"removeRR  ns1.example.org.",
+		"addRR  ns.example.net.",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+}
+
+func TestSetRecordsRollbackRemovesRecordsBesideSystemRecords(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/replaceRRSet":
+			w.WriteHeader(http.StatusNotFound)
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"}]`))
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("data"))
+
+			if r.PostForm.Get("data") == "ns2.example.org." {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}
+	})
+	p.RollbackOnError = true
+
+	records := []libdns.Record{
+		{Type: "NS", Name: "", Value: "ns1.example.org."},
+		{Type: "NS", Name: "", Value: "ns2.example.org."},
+	}
+
+	_, err := p.SetRecords(context.Background(), "example.com.", records)
+	var rollbackErr *RollbackError
+
+	if !errors.As(err, &rollbackErr) || rollbackErr.RollbackErr != nil {
+		t.Fatalf("Expected a successful rollback but got %v", err)
+	}
+
+	expected := []string{
+		"replaceRR ns1.example.org.",
+		"addRR ns2.example.org.",
+		"removeRR ns1.example.org.",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+}
@@ -0,0 +1,55 @@
+package nfsn
+
+import (
+	"time"
+)
+
+// How long a (timestamp, salt) pair is remembered. NFSN only accepts timestamps within a few seconds
+// of its clock, so a pair older than this can't be replayed anyway.
+const saltWindow = time.Minute
+
+// Generates salts. A variable so tests can replace it.
+var generateSalt = GenerateSalt
+
+// A (timestamp, salt) pair used to sign a request
+type usedSalt struct {
+	timestamp int64
+	salt      string
+}
+
+// Returns the timestamp and salt to sign a request with, guaranteeing that the pair hasn't been
+// used for another request made by the Provider. NFSN treats a reused pair as a replay, and many
+// requests are often signed within the same second.
+func (p *Provider) reserveSalt() (time.Time, string, error) {
+	timestamp := p.now()
+
+	p.usedSaltsMtx.Lock()
+	defer p.usedSaltsMtx.Unlock()
+
+	if p.usedSalts == nil {
+		p.usedSalts = make(map[usedSalt]struct{})
+	}
+
+	oldest := timestamp.Add(-saltWindow).Unix()
+
+	for used := range p.usedSalts {
+		if used.timestamp < oldest {
+			delete(p.usedSalts, used)
+		}
+	}
+
+	for {
+		salt, err := generateSalt(saltLen)
+
+		if err != nil {
+			return time.Time{}, "", err
+		}
+
+		used := usedSalt{timestamp: timestamp.Unix(), salt: salt}
+
+		if _, ok := p.usedSalts[used]; !ok {
+			p.usedSalts[used] = struct{}{}
+			return timestamp, salt, nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSaltsAreUnique(t *testing.T) {
+	var mtx sync.Mutex
+	seen := make(map[string]bool)
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.Header.Get(authHeader), ";")
+		pair := parts[1] + ";" + parts[2]
+
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if seen[pair] {
+			t.Errorf("Timestamp and salt '%s' were reused", pair)
+		}
+
+		seen[pair] = true
+		w.Write([]byte("[]"))
+	})
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			p.ValidateCredentials(context.Background())
+		}()
+	}
+
+	wg.Wait()
+
+	if len(seen) != 50 {
+		t.Errorf("Expected 50 requests but got %d", len(seen))
+	}
+}
+
+func TestReserveSaltRejectsReuse(t *testing.T) {
+	original := generateSalt
+	t.Cleanup(func() { generateSalt = original })
+
+	salts := []string{"AAAAAAAAAAAAAAAA", "AAAAAAAAAAAAAAAA", "BBBBBBBBBBBBBBBB"}
+	generateSalt = func(length int) (string, error) {
+		salt := salts[0]
+		salts = salts[1:]
+		return salt, nil
+	}
+
+	p := Provider{}
+	first, firstSalt, _ := p.reserveSalt()
+	second, secondSalt, _ := p.reserveSalt()
+
+	if first.Unix() == second.Unix() && firstSalt == secondSalt {
+		t.Errorf("Expected distinct pairs but got '%s' twice", firstSalt)
+	}
+}
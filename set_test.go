@@ -0,0 +1,93 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestSetRecordsReplacesEachGroup(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		if r.URL.Path == "/dns/example.com/replaceRRSet" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("type")+" "+r.PostForm.Get("name")+" "+r.PostForm.Get("data"))
+	})
+
+	records := []libdns.Record{
+		{Type: "A", Name: "", Value: "192.0.2.1"},
+		{Type: "MX", Name: "", Value: "mail1.example.com.", Priority: 10},
+		{Type: "A", Name: "", Value: "192.0.2.2"},
+		{Type: "MX", Name: "", Value: "mail2.example.com.", Priority: 20},
+		{Type: "SRV", Name: "_sip._tcp", Value: "5060 sip.example.com.", Priority: 10, Weight: 5},
+	}
+
+	replaced, err := p.SetRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"replaceRR A  192.0.2.1",
+		"addRR A  192.0.2.2",
+		"replaceRR MX  10 mail1.example.com.",
+		"addRR MX  20 mail2.example.com.",
+		"replaceRR SRV _sip._tcp 10 5 5060 sip.example.com.",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+
+	if len(replaced) != len(records) {
+		t.Errorf("Expected %d records but got %d", len(records), len(replaced))
+	}
+}
+
+func TestInvalidRecordPreventsAllChanges(t *testing.T) {
+	requests := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+
+	// Checks that would otherwise read the zone first
+	p.CheckConflicts = true
+	p.RollbackOnError = true
+
+	records := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.1"},
+		{Type: "CAA", Name: "", Value: "issue letsencrypt.org"},
+		{Type: "A", Name: "mail", Value: "192.0.2.2"},
+	}
+
+	for _, apply := range []func(context.Context, string, []libdns.Record) ([]libdns.Record, error){p.AppendRecords, p.SetRecords, p.DeleteRecords} {
+		processed, err := apply(context.Background(), "example.com.", records)
+
+		if err == nil || !strings.Contains(err.Error(), "Record 2 of 3") {
+			t.Errorf("Expected an error identifying record 2 but got %v", err)
+		}
+
+		if len(processed) != 0 || requests != 0 {
+			t.Errorf("Expected no requests but %d were made", requests)
+		}
+	}
+
+	// Deletions by name and type are looked up only once the other records are known to be valid
+	records[0].Value = ""
+
+	if _, err := p.DeleteRecords(context.Background(), "example.com.", records); err == nil || !strings.Contains(err.Error(), "Record 2 of 3") || requests != 0 {
+		t.Errorf("Expected an error without requests but got %v after %d requests", err, requests)
+	}
+}
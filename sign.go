@@ -0,0 +1,54 @@
+package nfsn
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SignedRequest is the authentication information for a request, as computed by `SignRequest`.
+type SignedRequest struct {
+	// Name of the header that carries the signature, i.e. X-NFSN-Authentication
+	HeaderName string
+
+	// Value of the X-NFSN-Authentication header
+	HeaderValue string
+
+	// The exact request body that was signed
+	Body []byte
+}
+
+// SignRequest computes the X-NFSN-Authentication header for `req` using the Provider's credentials
+// and the given `timestamp` and `salt`, without making any network requests. This allows the exact
+// bytes and signature of an operation to be reviewed (e.g. by an auditor without network access) or
+// replayed. `req` is not modified, except that its body is replaced with an equivalent one.
+//
+// NFSN rejects a salt that is reused, so a fresh one must be used for each request that will
+// actually be sent. See `innerGetAuthValue` for the format of the header.
+func (p *Provider) SignRequest(req *http.Request, timestamp time.Time, salt string) (SignedRequest, error) {
+	var body []byte
+
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+
+		if err != nil {
+			return SignedRequest{}, err
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	headerValue, err := p.innerGetAuthValue(req, timestamp, salt)
+
+	if err != nil {
+		return SignedRequest{}, err
+	}
+
+	return SignedRequest{
+		HeaderName:  authHeader,
+		HeaderValue: headerValue,
+		Body:        body,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package nfsn
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignRequest(t *testing.T) {
+	p := Provider{
+		Login:  "testuser",
+		APIKey: "p3kxmRKf9dk3l6ls",
+	}
+
+	// Same vector as TestGetAuthValue
+	req, err := http.NewRequest("GET", "https://api.nearlyfreespeech.net/site/example/getInfo", nil)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	signed, err := p.SignRequest(req, time.Unix(1012121212, 0), "dkwo28Sile4jdXkw")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := "testuser;1012121212;dkwo28Sile4jdXkw;0fa8932e122d56e2f6d1550f9aab39c4aef8bfc4"
+
+	if signed.HeaderName != "X-NFSN-Authentication" || signed.HeaderValue != expected {
+		t.Errorf("Expected '%s' but got '%s: %s'", expected, signed.HeaderName, signed.HeaderValue)
+	}
+
+	if len(signed.Body) != 0 {
+		t.Errorf("Expected an empty body but got '%s'", signed.Body)
+	}
+
+	body := "name=www&type=A&data=192.0.2.1&ttl=180"
+	req, err = http.NewRequest("POST", "https://api.nearlyfreespeech.net/dns/example.com/addRR", strings.NewReader(body))
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	signed, err = p.SignRequest(req, time.Unix(1012121212, 0), "dkwo28Sile4jdXkw")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if string(signed.Body) != body {
+		t.Errorf("Expected '%s' but got '%s'", body, signed.Body)
+	}
+
+	inner, _ := p.innerGetAuthValue(req, time.Unix(1012121212, 0), "dkwo28Sile4jdXkw")
+
+	if signed.HeaderValue != inner {
+		t.Errorf("Expected '%s' but got '%s'", inner, signed.HeaderValue)
+	}
+}
@@ -0,0 +1,39 @@
+package nfsn
+
+import (
+	"net/http"
+)
+
+// AuthSigner authenticates API requests. By default requests are signed with an
+// X-NFSN-Authentication header computed from the Provider's credentials (see `SignRequest`); an
+// AuthSigner replaces that, e.g. to use session tokens or a future NFSN authentication scheme.
+type AuthSigner interface {
+	// Sign adds authentication to `req`, typically by setting a header. It may read the request
+	// body, but must leave an equivalent body in place.
+	Sign(req *http.Request) error
+}
+
+// AuthSignerFunc adapts an ordinary function to the AuthSigner interface.
+type AuthSignerFunc func(req *http.Request) error
+
+// Sign calls f(req).
+func (f AuthSignerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+// Adds authentication to `req`, with AuthSigner if it's set or the X-NFSN-Authentication header
+// otherwise. Returns the API key the header was computed with, if any (see `getAuthValue`).
+func (p *Provider) sign(req *http.Request) (string, error) {
+	if p.AuthSigner != nil {
+		return "", p.AuthSigner.Sign(req)
+	}
+
+	authValue, apiKey, err := p.getAuthValue(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set(authHeader, authValue)
+	return apiKey, nil
+}
@@ -0,0 +1,35 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestAuthSigner(t *testing.T) {
+	var nfsnAuth, token string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		nfsnAuth = r.Header.Get(authHeader)
+		token = r.Header.Get("Authorization")
+		w.Write([]byte("[]"))
+	})
+	p.AuthSigner = AuthSignerFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer session-token")
+		return nil
+	})
+
+	_, err := p.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if nfsnAuth != "" {
+		t.Errorf("Expected no X-NFSN-Authentication header but got '%s'", nfsnAuth)
+	}
+
+	if token != "Bearer session-token" {
+		t.Errorf("Expected 'Bearer session-token' but got '%s'", token)
+	}
+}
@@ -0,0 +1,94 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// Number of times GetSnapshot reads the zone before giving up on getting a consistent view
+const snapshotAttempts = 3
+
+// Snapshot is the content of a zone as of a single point in time.
+type Snapshot struct {
+	Records []libdns.Record
+
+	// The SOA serial of the zone when Records were read. Comparing it against a later call to
+	// GetSnapshot reveals whether the zone has changed in between.
+	Serial uint32
+}
+
+// GetSnapshot reads the records in the zone along with its SOA serial.
+//
+// A single `listRRs` request is already an atomic read of the zone, but the serial is a separate
+// request. The serial is read before and after the records and the read is repeated if it changed,
+// so the returned serial always describes the returned records. As with GetRecords, records that
+// can't be converted are reported via a `RecordParseErrors` returned alongside the snapshot.
+func (p *Provider) GetSnapshot(ctx context.Context, zone string) (Snapshot, error) {
+	for attempt := 0; attempt < snapshotAttempts; attempt++ {
+		before, err := p.getSerial(ctx, zone)
+
+		if err != nil {
+			return Snapshot{}, err
+		}
+
+		records, recordsErr := p.GetRecords(ctx, zone)
+		var parseErrors RecordParseErrors
+
+		if recordsErr != nil && !errors.As(recordsErr, &parseErrors) {
+			return Snapshot{}, recordsErr
+		}
+
+		after, err := p.getSerial(ctx, zone)
+
+		if err != nil {
+			return Snapshot{}, err
+		}
+
+		if before == after {
+			return Snapshot{Records: records, Serial: after}, recordsErr
+		}
+	}
+
+	return Snapshot{}, fmt.Errorf("Zone %s changed on every one of %d attempts to read it", zone, snapshotAttempts)
+}
+
+// Reads the `serial` property of the zone.
+func (p *Provider) getSerial(ctx context.Context, zone string) (uint32, error) {
+	serialText, err := p.getZoneProperty(ctx, zone, "serial")
+
+	if err != nil {
+		return 0, err
+	}
+
+	serial, err := strconv.ParseUint(serialText, 10, 32)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse serial %q for zone %s: %w", serialText, zone, err)
+	}
+
+	return uint32(serial), nil
+}
+
+// Reads the property `name` of the zone.
+func (p *Provider) getZoneProperty(ctx context.Context, zone string, name string) (string, error) {
+	resp, err := p.makeRequest(ctx, "GET", p.uriForZone(zone, name), nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return "", err
+	}
+
+	// Properties may be returned bare or as a JSON string
+	return strings.Trim(strings.TrimSpace(string(bodyBytes)), `"`), nil
+}
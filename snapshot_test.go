@@ -0,0 +1,41 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGetSnapshot(t *testing.T) {
+	serials := []string{"2024010100", "2024010101", "2024010101", "2024010101"}
+	serialReads := 0
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns/example.com/serial":
+			w.Write([]byte(serials[serialReads]))
+			serialReads++
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"}]`))
+		}
+	})
+
+	snapshot, err := p.GetSnapshot(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// The first read saw the serial change, so the zone must have been read twice
+	if serialReads != 4 {
+		t.Errorf("Expected 4 serial reads but got %d", serialReads)
+	}
+
+	if snapshot.Serial != 2024010101 {
+		t.Errorf("Expected serial 2024010101 but got %d", snapshot.Serial)
+	}
+
+	if len(snapshot.Records) != 1 || snapshot.Records[0].Value != "192.0.2.1" {
+		t.Errorf("Unexpected records %+v", snapshot.Records)
+	}
+}
@@ -0,0 +1,59 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ZoneStats summarizes the records in a zone.
+type ZoneStats struct {
+	// The number of records, not counting those that couldn't be parsed
+	Records int
+
+	// The number of records of each type, e.g. "A"
+	ByType map[string]int
+
+	// The number of records in each NFSN scope, e.g. "member" or "system" (see `RecordScope`)
+	ByScope map[string]int
+
+	// The lowest and highest TTLs of the records, or zero if there are none
+	MinTTL time.Duration
+	MaxTTL time.Duration
+
+	// The number of records NFSN returned that couldn't be converted (see `RecordParseErrors`)
+	Unparseable int
+}
+
+// ZoneStats reads the zone and summarizes its records, e.g. for a dashboard or as a sanity check
+// before a bulk change. Records that can't be parsed are counted rather than reported as an error.
+func (p *Provider) ZoneStats(ctx context.Context, zone string) (ZoneStats, error) {
+	records, err := p.getRecords(ctx, zone, "", "")
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return ZoneStats{}, err
+	}
+
+	stats := ZoneStats{
+		Records:     len(records),
+		ByType:      make(map[string]int),
+		ByScope:     make(map[string]int),
+		Unparseable: len(parseErrors),
+	}
+
+	for i, record := range records {
+		stats.ByType[record.Type]++
+		stats.ByScope[RecordScope(record)]++
+
+		if i == 0 || record.TTL < stats.MinTTL {
+			stats.MinTTL = record.TTL
+		}
+
+		if record.TTL > stats.MaxTTL {
+			stats.MaxTTL = record.TTL
+		}
+	}
+
+	return stats, nil
+}
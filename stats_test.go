@@ -0,0 +1,39 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestZoneStats(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+			{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 600, "scope": "member"},
+			{"name": "mail", "type": "A", "data": "192.0.2.2", "ttl": 86400, "scope": "member"},
+			{"name": "_sip._tcp", "type": "SRV", "data": "heavy 5060 sip.example.com.", "ttl": 3600, "scope": "member", "aux": 10}
+		]`))
+	})
+
+	stats, err := p.ZoneStats(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := ZoneStats{
+		Records:     3,
+		ByType:      map[string]int{"NS": 1, "A": 2},
+		ByScope:     map[string]int{"system": 1, "member": 2},
+		MinTTL:      10 * time.Minute,
+		MaxTTL:      24 * time.Hour,
+		Unparseable: 1,
+	}
+
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, stats)
+	}
+}
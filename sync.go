@@ -0,0 +1,246 @@
+package nfsn
+
+import (
+	"context"
+
+	"github.com/libdns/libdns"
+)
+
+// SyncOptions controls which records SyncZone manages.
+type SyncOptions struct {
+	// Only (name, type) sets of these types are changed. If empty, every type is managed.
+	Types []string
+
+	// If true, sets in the zone that have no records in `desired` are left as they are, so the
+	// desired records only need to describe the sets being managed. Otherwise they're deleted.
+	KeepUnlisted bool
+}
+
+// Returns true if `opts` allows SyncZone to change records of type `rType`.
+func (opts SyncOptions) manages(rType string) bool {
+	if len(opts.Types) == 0 {
+		return true
+	}
+
+	for _, managed := range opts.Types {
+		if Normalize(libdns.Record{Type: managed}).Type == rType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SyncResult lists the records SyncZone changed.
+type SyncResult struct {
+	// Records added to sets that otherwise didn't change
+	Added []libdns.Record
+
+	// The new contents of sets that were replaced, because records were both added to and removed
+	// from them or their TTLs changed
+	Updated []libdns.Record
+
+	// Records removed from the zone
+	Deleted []libdns.Record
+}
+
+// The changes needed to bring a zone in line with a set of desired records. Each (name, type) set
+// needs at most one kind of change.
+type syncPlan struct {
+	remove  []libdns.Record
+	replace []libdns.Record
+	add     []libdns.Record
+}
+
+// SyncZone makes the zone contain exactly the `desired` records, within the limits of `opts`, by
+// comparing them with the records in the zone and applying only the differences. Records are
+// compared as they would be stored (see `Equal`), so calling SyncZone again with the same records
+// makes no changes. A desired record without a TTL matches a record with any TTL, unless
+// `DefaultTTL` is set.
+//
+// Each (name, type) set is changed as a unit: records only missing from a set are added, records
+// only extra in a set are deleted, and a set that needs both, or a new TTL, is replaced. Deletions
+// are made first, then replacements, then additions. Records managed by NFSN (system and read-only
+// records) are never changed.
+//
+// Every desired record is validated before any change is made. The zone is read first, and if any
+// of its records can't be parsed the `RecordParseErrors` is returned without making any changes,
+// since the differences can't be known. In the case where only some changes are made returns both
+// the changes that were made and an error.
+//
+// If `VerifyAfterWrite` is enabled, Added and Updated are the (name, type) sets that were changed as
+// NFSN stored them, read back after the last change.
+func (p *Provider) SyncZone(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) (SyncResult, error) {
+	plan, err := p.planSync(ctx, zone, desired, opts)
+
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	var result SyncResult
+
+	if len(plan.remove) > 0 {
+		result.Deleted, err = p.processRecords(ctx, zone, "removeRR", plan.remove)
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if len(plan.replace) > 0 {
+		result.Updated, err = p.replaceGroups(ctx, zone, plan.replace)
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if len(plan.add) > 0 {
+		result.Added, err = p.processRecords(ctx, zone, "addRR", plan.add)
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if p.VerifyAfterWrite && len(result.Added)+len(result.Updated) > 0 {
+		stored, err := p.storedSets(ctx, zone, result.Added, result.Updated)
+
+		if err != nil {
+			return result, err
+		}
+
+		result.Added, result.Updated = stored[0], stored[1]
+	}
+
+	return result, nil
+}
+
+// Works out the changes SyncZone makes.
+func (p *Provider) planSync(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) (syncPlan, error) {
+	desired, err := relativeRecords(zone, desired)
+
+	if err != nil {
+		return syncPlan{}, err
+	}
+
+	ctx = p.withZoneMinTTL(ctx, zone)
+	_, err = p.validateRecords(ctx, desired)
+
+	if err != nil {
+		return syncPlan{}, err
+	}
+
+	current, err := p.getRecords(ctx, zone, "", "")
+
+	if err != nil {
+		return syncPlan{}, err
+	}
+
+	var keys []rrSetKey
+	desiredSets := make(map[rrSetKey][]libdns.Record)
+	currentSets := make(map[rrSetKey][]libdns.Record)
+	fixedSets := make(map[rrSetKey][]libdns.Record)
+
+	for _, record := range desired {
+		key := rrSetKeyFor(record)
+
+		if !opts.manages(key.rType) {
+			continue
+		}
+
+		if _, ok := desiredSets[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		desiredSets[key] = append(desiredSets[key], record)
+	}
+
+	for _, record := range current {
+		key := rrSetKeyFor(record)
+
+		if IsSystemRecord(record) || checkReadOnly(record) != nil {
+			fixedSets[key] = append(fixedSets[key], record)
+			continue
+		}
+
+		if !opts.manages(key.rType) {
+			continue
+		}
+
+		if _, ok := desiredSets[key]; !ok {
+			if opts.KeepUnlisted {
+				continue
+			}
+
+			if _, ok := currentSets[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		currentSets[key] = append(currentSets[key], record)
+	}
+
+	var plan syncPlan
+
+	for _, key := range keys {
+		// Desired records NFSN already manages itself don't need to be written
+		missing := p.differentRecords(ctx, desiredSets[key], append(currentSets[key], fixedSets[key]...))
+		extra := p.differentRecords(ctx, currentSets[key], desiredSets[key])
+
+		switch {
+		case len(missing) == 0:
+			plan.remove = append(plan.remove, extra...)
+		case len(extra) == 0:
+			plan.add = append(plan.add, missing...)
+		case len(fixedSets[key]) > 0:
+			// Replacing the set would replace the records NFSN manages too
+			plan.remove = append(plan.remove, extra...)
+			plan.add = append(plan.add, missing...)
+		default:
+			plan.replace = append(plan.replace, desiredSets[key]...)
+		}
+	}
+
+	return plan, nil
+}
+
+// Returns the records in `a` that aren't in `b`, comparing them as they would be stored (see
+// `syncEqual`).
+func (p *Provider) differentRecords(ctx context.Context, a []libdns.Record, b []libdns.Record) []libdns.Record {
+	var different []libdns.Record
+
+	for _, record := range a {
+		found := false
+
+		for _, other := range b {
+			if p.syncEqual(ctx, record, other) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			different = append(different, record)
+		}
+	}
+
+	return different
+}
+
+// Returns true if `a` and `b` are the same record once normalized, with their TTLs as they'd be
+// written. A record that would be written without a TTL takes NFSN's default, which can't be
+// known, so it matches a record with any TTL. TTLs are compared against the zone's own minimum
+// rather than the default one `Normalize` applies.
+func (p *Provider) syncEqual(ctx context.Context, a libdns.Record, b libdns.Record) bool {
+	a.TTL = p.ttlForNfsn(ctx, a.TTL)
+	b.TTL = p.ttlForNfsn(ctx, b.TTL)
+
+	if a.TTL != 0 && b.TTL != 0 && a.TTL != b.TTL {
+		return false
+	}
+
+	// The TTLs match, so they're left out of the rest of the comparison
+	a.TTL, b.TTL = 0, 0
+	return Equal(a, b)
+}
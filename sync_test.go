@@ -0,0 +1,128 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+const syncTestZone = `[
+	{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+	{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+	{"name": "", "type": "MX", "data": "mail1.example.com.", "ttl": 3600, "scope": "member", "aux": 10},
+	{"name": "", "type": "TXT", "data": "v=spf1 -all", "ttl": 3600, "scope": "member"},
+	{"name": "", "type": "TXT", "data": "stale", "ttl": 3600, "scope": "member"},
+	{"name": "old", "type": "CNAME", "data": "www.example.com.", "ttl": 3600, "scope": "member"}
+]`
+
+// Returns a provider backed by a server holding `syncTestZone`, along with a pointer to the list of
+// "verb type name data ttl" strings for each change it was asked to make.
+func newSyncTestProvider(t *testing.T) (*Provider, *[]string) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(syncTestZone))
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("type")+" "+r.PostForm.Get("name")+" "+r.PostForm.Get("data")+" "+r.PostForm.Get("ttl"))
+		}
+	})
+
+	return p, &mutations
+}
+
+func TestSyncZone(t *testing.T) {
+	p, mutations := newSyncTestProvider(t)
+
+	desired := []libdns.Record{
+		{Type: "NS", Name: "", Value: "ns.phx1.nearlyfreespeech.net.", TTL: time.Hour},
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 10 * time.Minute},
+		{Type: "MX", Name: "", Value: "mail1.example.com.", Priority: 10, TTL: time.Hour},
+		{Type: "MX", Name: "", Value: "mail2.example.com.", Priority: 20, TTL: time.Hour},
+		{Type: "TXT", Name: "", Value: "v=spf1 -all"},
+	}
+
+	result, err := p.SyncZone(context.Background(), "example.com.", desired, SyncOptions{})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"removeRR TXT  stale 3600",
+		"removeRR CNAME old www.example.com. 3600",
+		"replaceRR A www 192.0.2.1 600",
+		"addRR MX  20 mail2.example.com. 3600",
+	}
+
+	if !reflect.DeepEqual(*mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, *mutations)
+	}
+
+	if len(result.Deleted) != 2 || len(result.Updated) != 1 || len(result.Added) != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestSyncZoneOptions(t *testing.T) {
+	p, mutations := newSyncTestProvider(t)
+
+	desired := []libdns.Record{
+		{Type: "TXT", Name: "", Value: "v=spf1 -all"},
+		{Type: "A", Name: "new", Value: "192.0.2.2"},
+	}
+
+	_, err := p.SyncZone(context.Background(), "example.com.", desired, SyncOptions{Types: []string{"txt"}, KeepUnlisted: true})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if expected := []string{"removeRR TXT  stale 3600"}; !reflect.DeepEqual(*mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, *mutations)
+	}
+
+	*mutations = nil
+	_, err = p.SyncZone(context.Background(), "example.com.", desired[1:], SyncOptions{KeepUnlisted: true})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if expected := []string{"addRR A new 192.0.2.2 "}; !reflect.DeepEqual(*mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, *mutations)
+	}
+}
+
+func TestSyncZoneVerifyAfterWrite(t *testing.T) {
+	p, _ := newSyncTestProvider(t)
+	p.VerifyAfterWrite = true
+
+	desired := []libdns.Record{
+		{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 10 * time.Minute},
+		{Type: "A", Name: "new", Value: "192.0.2.2"},
+	}
+
+	result, err := p.SyncZone(context.Background(), "example.com.", desired, SyncOptions{KeepUnlisted: true})
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// The test server's zone never changes, so the re-fetched www set still has its old TTL and the
+	// new record isn't there
+	if len(result.Updated) != 1 || result.Updated[0].Name != "www" || result.Updated[0].TTL != time.Hour {
+		t.Errorf("Expected the www set as stored but got %+v", result.Updated)
+	}
+
+	if len(result.Added) != 0 {
+		t.Errorf("Expected no stored records for the new set but got %+v", result.Added)
+	}
+}
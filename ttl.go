@@ -0,0 +1,239 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// SetZoneTTL sets the TTL of every member-editable record in the zone to `ttl`, preserving the
+// records' data. Records managed by NFSN (those with the "system" scope, and DS and DNSKEY records)
+// are left untouched. TTLs below the minimum (see `MinTTL`) are raised to the minimum. A TTL of zero
+// applies `DefaultTTL`, or NFSN's own default if that isn't set.
+//
+// Sets that hold only editable records are replaced. In sets that also hold records managed by NFSN,
+// which replacing would remove, each editable record is removed and added again with the new TTL.
+//
+// Returns the updated records. In the case where only some records are updated returns both the
+// records that were updated and an error. Records that can't be parsed are skipped and reported in
+// a `RecordParseErrors`.
+func (p *Provider) SetZoneTTL(ctx context.Context, zone string, ttl time.Duration) ([]libdns.Record, error) {
+	nRecords, err := p.listRecords(ctx, zone, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var editable []nfsnRecord
+	fixedSets := make(map[rrSetKey]bool)
+
+	for _, nRecord := range nRecords {
+		if nRecord.Scope != systemScope && !readOnlyTypes[nRecord.Type] {
+			editable = append(editable, nRecord)
+		} else {
+			fixedSets[rrSetKeyFor(libdns.Record{Name: nRecord.Name, Type: nRecord.Type})] = true
+		}
+	}
+
+	records, parseErr := p.toLibdnsRecords(editable)
+
+	ctx = p.withZoneMinTTL(ctx, zone)
+	ttl = p.ttlForNfsn(ctx, ttl)
+
+	var replace, remove, add []libdns.Record
+
+	for _, record := range records {
+		updated := record
+		updated.TTL = ttl
+
+		if fixedSets[rrSetKeyFor(record)] {
+			remove = append(remove, record)
+			add = append(add, updated)
+		} else {
+			replace = append(replace, updated)
+		}
+	}
+
+	var updated []libdns.Record
+
+	if len(replace) > 0 {
+		updated, err = p.replaceGroups(ctx, zone, replace)
+
+		if err != nil {
+			return updated, err
+		}
+	}
+
+	if len(remove) > 0 {
+		_, err = p.processRecords(ctx, zone, "removeRR", remove)
+
+		if err != nil {
+			return updated, err
+		}
+
+		added, err := p.processRecords(ctx, zone, "addRR", add)
+		updated = append(updated, added...)
+
+		if err != nil {
+			return updated, err
+		}
+	}
+
+	return updated, parseErr
+}
+
+// ErrTTLBelowMinimum is returned, when `Provider.StrictTTL` is enabled, for records with a TTL
+// below the minimum.
+var ErrTTLBelowMinimum = errors.New("TTL below minimum")
+
+type minTTLKey struct{}
+
+// WithMinTTL returns a context that overrides `Provider.MinTTL` for calls made with it, e.g. to use
+// a lower TTL for a short-lived ACME challenge record. NFSN enforces its own minimum server-side
+// regardless, so a TTL below the one NFSN allows for the account will be rejected by the API.
+func WithMinTTL(ctx context.Context, minTTL time.Duration) context.Context {
+	return context.WithValue(ctx, minTTLKey{}, minTTL)
+}
+
+// Returns the TTL to send to NFSN for a record with `ttl`, raising it to the minimum in effect for
+// the call. A TTL of zero is replaced with `DefaultTTL`; if that isn't set either, zero is returned
+// and the ttl parameter should be left out so NFSN applies its default.
+func (p *Provider) ttlForNfsn(ctx context.Context, ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		ttl = p.DefaultTTL
+	}
+
+	if ttl == 0 {
+		return 0
+	}
+
+	minTTL := p.minTTL(ctx)
+
+	if ttl < minTTL {
+		return minTTL
+	}
+
+	return ttl
+}
+
+// Returns the minimum TTL in effect for the call.
+func (p *Provider) minTTL(ctx context.Context) time.Duration {
+	if override, ok := ctx.Value(minTTLKey{}).(time.Duration); ok {
+		return override
+	}
+
+	if p.MinTTL > 0 {
+		return p.MinTTL
+	}
+
+	if discovered, ok := ctx.Value(zoneMinTTLKey{}).(zoneMinTTL); ok {
+		return discovered.ttl
+	}
+
+	return minimumTTL
+}
+
+type zoneMinTTLKey struct{}
+
+// The minimum TTL of the zone a call is for, see `withZoneMinTTL`
+type zoneMinTTL struct {
+	zone string
+	ttl  time.Duration
+}
+
+// Returns a context carrying the minimum TTL NFSN allows for `zone`, as reported by its `minTTL`
+// property. The property is read once per zone and cached on the Provider. Nothing is read if the
+// minimum is configured with `MinTTL` or `WithMinTTL`. If the property can't be read, the default
+// minimum of 3 minutes applies to this call, and the property is read again on the next.
+func (p *Provider) withZoneMinTTL(ctx context.Context, zone string) context.Context {
+	if _, ok := ctx.Value(minTTLKey{}).(time.Duration); ok || p.MinTTL > 0 {
+		return ctx
+	}
+
+	// Already looked up for this call
+	if discovered, ok := ctx.Value(zoneMinTTLKey{}).(zoneMinTTL); ok && discovered.zone == zone {
+		return ctx
+	}
+
+	p.zoneMinTTLsMtx.Lock()
+	minTTL, ok := p.zoneMinTTLs[zone]
+	p.zoneMinTTLsMtx.Unlock()
+
+	if !ok {
+		minTTL, ok = p.readZoneMinTTL(ctx, zone)
+
+		if !ok {
+			return context.WithValue(ctx, zoneMinTTLKey{}, zoneMinTTL{zone, minTTL})
+		}
+
+		p.zoneMinTTLsMtx.Lock()
+
+		if p.zoneMinTTLs == nil {
+			p.zoneMinTTLs = make(map[string]time.Duration)
+		}
+
+		p.zoneMinTTLs[zone] = minTTL
+		p.zoneMinTTLsMtx.Unlock()
+	}
+
+	return context.WithValue(ctx, zoneMinTTLKey{}, zoneMinTTL{zone, minTTL})
+}
+
+type removalKey struct{}
+
+// Returns a context for converting records that are to be removed, whose TTLs don't matter, so
+// `checkStrictTTL` lets them through.
+func withRemoval(ctx context.Context) context.Context {
+	return context.WithValue(ctx, removalKey{}, true)
+}
+
+// Returns an error wrapping ErrTTLBelowMinimum if `StrictTTL` is enabled and `record` has a TTL
+// that would be raised to the minimum. A TTL of zero is taken to mean the record has no particular
+// TTL; `DefaultTTL` is checked in its place, and NFSN's default is always allowed. Records being
+// removed (see `withRemoval`) aren't checked.
+func (p *Provider) checkStrictTTL(ctx context.Context, record libdns.Record) error {
+	if removal, _ := ctx.Value(removalKey{}).(bool); removal {
+		return nil
+	}
+
+	ttl := record.TTL
+
+	if ttl == 0 {
+		ttl = p.DefaultTTL
+	}
+
+	if !p.StrictTTL || ttl == 0 {
+		return nil
+	}
+
+	minTTL := p.minTTL(ctx)
+
+	if ttl < minTTL {
+		return fmt.Errorf("%w: %s record %q has TTL %s, but the minimum is %s", ErrTTLBelowMinimum, record.Type, record.Name, ttl, minTTL)
+	}
+
+	return nil
+}
+
+// Reads the `minTTL` property of the zone. Returns minimumTTL and false if it can't be read.
+func (p *Provider) readZoneMinTTL(ctx context.Context, zone string) (time.Duration, bool) {
+	minTTLText, err := p.getZoneProperty(ctx, zone, "minTTL")
+
+	if err != nil {
+		p.logf("Failed to read the minimum TTL for zone %s, using %s: %v", zone, minimumTTL, err)
+		return minimumTTL, false
+	}
+
+	seconds, err := strconv.Atoi(minTTLText)
+
+	if err != nil || seconds <= 0 {
+		p.logf("Invalid minimum TTL %q for zone %s, using %s", minTTLText, zone, minimumTTL)
+		return minimumTTL, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,331 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestSetZoneTTL(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[
+				{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+				{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"},
+				{"name": "", "type": "TXT", "data": "one", "ttl": 600, "scope": "member"},
+				{"name": "", "type": "TXT", "data": "two", "ttl": 600, "scope": "member"}
+			]`))
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("type")+" "+r.PostForm.Get("data")+" "+r.PostForm.Get("ttl"))
+		}
+	})
+
+	updated, err := p.SetZoneTTL(context.Background(), "example.com.", 900*time.Second)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{
+		"replaceRR A 192.0.2.1 900",
+		"replaceRR TXT one 900",
+		"addRR TXT two 900",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+
+	for _, record := range updated {
+		if record.TTL != 900*time.Second {
+			t.Errorf("Expected TTL 900s for %+v", record)
+		}
+	}
+}
+
+func TestMinTTLOverride(t *testing.T) {
+	p := &Provider{MinTTL: 10 * time.Minute}
+	record := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: time.Minute}
+
+	if ttl := mustParameters(t, p, context.Background(), record).Get("ttl"); ttl != "600" {
+		t.Errorf("Expected '600' but got '%s'", ttl)
+	}
+
+	ctx := WithMinTTL(context.Background(), 30*time.Second)
+
+	if ttl := mustParameters(t, p, ctx, record).Get("ttl"); ttl != "60" {
+		t.Errorf("Expected '60' but got '%s'", ttl)
+	}
+
+	if ttl := mustParameters(t, &Provider{}, context.Background(), record).Get("ttl"); ttl != "180" {
+		t.Errorf("Expected '180' but got '%s'", ttl)
+	}
+}
+
+func TestStrictTTL(t *testing.T) {
+	var ttls []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		ttls = append(ttls, r.PostForm.Get("ttl"))
+	})
+
+	records := []libdns.Record{
+		{Type: "A", Name: "a", Value: "192.0.2.1", TTL: time.Hour},
+		{Type: "A", Name: "b", Value: "192.0.2.2", TTL: time.Minute},
+	}
+
+	// Clamped by default
+	_, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"3600", "180"}
+
+	if !reflect.DeepEqual(ttls, expected) {
+		t.Errorf("Expected %v but got %v", expected, ttls)
+	}
+
+	ttls = nil
+	p.StrictTTL = true
+	added, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if !errors.Is(err, ErrTTLBelowMinimum) {
+		t.Fatalf("Expected ErrTTLBelowMinimum but got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), `"b"`) {
+		t.Errorf("Expected the error to identify the record but got %v", err)
+	}
+
+	if len(added) != 0 || len(ttls) != 0 {
+		t.Errorf("Expected no records to be written but %d were", len(ttls))
+	}
+}
+
+func TestStrictTTLAllowsDeletion(t *testing.T) {
+	var removed []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[{"name": "_acme-challenge", "type": "TXT", "data": "token", "ttl": 60, "scope": "member"}]`))
+		case "/dns/example.com/removeRR":
+			removed = append(removed, r.PostForm.Get("data"))
+		}
+	})
+	p.StrictTTL = true
+
+	// A TTL below the minimum doesn't matter for a record that's being removed
+	record := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: time.Minute}
+	_, err := p.DeleteRecords(context.Background(), "example.com.", []libdns.Record{record})
+
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	_, err = p.DeleteAllRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	if len(removed) != 2 {
+		t.Errorf("Expected the record to be removed twice but got %v", removed)
+	}
+}
+
+func TestDefaultTTL(t *testing.T) {
+	record := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token"}
+
+	if params := mustParameters(t, &Provider{}, context.Background(), record); params.Has("ttl") {
+		t.Errorf("Expected no ttl parameter but got '%s'", params.Get("ttl"))
+	}
+
+	p := &Provider{DefaultTTL: time.Hour}
+
+	if ttl := mustParameters(t, p, context.Background(), record).Get("ttl"); ttl != "3600" {
+		t.Errorf("Expected '3600' but got '%s'", ttl)
+	}
+
+	// The default is still subject to the minimum
+	p = &Provider{DefaultTTL: time.Minute, StrictTTL: true}
+
+	if _, err := p.toNfsnRecordParameters(context.Background(), record); !errors.Is(err, ErrTTLBelowMinimum) {
+		t.Errorf("Expected ErrTTLBelowMinimum but got %v", err)
+	}
+
+	record.TTL = 5 * time.Minute
+
+	if ttl := mustParameters(t, p, context.Background(), record).Get("ttl"); ttl != "300" {
+		t.Errorf("Expected '300' but got '%s'", ttl)
+	}
+}
+
+func TestZoneMinTTL(t *testing.T) {
+	var paths, ttls []string
+	minTTL := "60"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		paths = append(paths, r.URL.Path)
+
+		if strings.HasSuffix(r.URL.Path, "/minTTL") {
+			w.Write([]byte(minTTL))
+			return
+		}
+
+		ttls = append(ttls, r.PostForm.Get("ttl"))
+	}))
+	defer server.Close()
+
+	p := &Provider{Login: "testuser", APIKey: "p3kxmRKf9dk3l6ls", HTTPClient: server.Client(), baseURL: server.URL}
+	record := libdns.Record{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: 30 * time.Second}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{record}); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	expected := []string{"/dns/example.com/minTTL", "/dns/example.com/addRR", "/dns/example.com/addRR"}
+
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected %v but got %v", expected, paths)
+	}
+
+	if !reflect.DeepEqual(ttls, []string{"60", "60"}) {
+		t.Errorf("Expected the zone's minimum TTL but got %v", ttls)
+	}
+
+	// An unreadable minimum falls back to the default, and is read again on the next call
+	paths, ttls = nil, nil
+	minTTL = "unlimited"
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.AppendRecords(context.Background(), "example.net.", []libdns.Record{record}); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if len(paths) != 4 || !reflect.DeepEqual(ttls, []string{"180", "180"}) {
+		t.Errorf("Expected the default minimum TTL but got %v, %v", paths, ttls)
+	}
+
+	// Until it can be read
+	paths, ttls = nil, nil
+	minTTL = "120"
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.AppendRecords(context.Background(), "example.net.", []libdns.Record{record}); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+
+	if len(paths) != 3 || !reflect.DeepEqual(ttls, []string{"120", "120"}) {
+		t.Errorf("Expected the zone's minimum TTL but got %v, %v", paths, ttls)
+	}
+
+	// A configured minimum is used without reading the zone's
+	paths, ttls = nil, nil
+	p.MinTTL = 5 * time.Minute
+
+	if _, err := p.AppendRecords(context.Background(), "example.org.", []libdns.Record{record}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(paths) != 1 || !reflect.DeepEqual(ttls, []string{"300"}) {
+		t.Errorf("Expected the configured minimum TTL but got %v, %v", paths, ttls)
+	}
+}
+
+func TestStrictTTLUsesZoneMinTTL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/minTTL"):
+			w.Write([]byte("60"))
+		case strings.HasSuffix(r.URL.Path, "/replaceRRSet"):
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Provider{Login: "testuser", APIKey: "p3kxmRKf9dk3l6ls", HTTPClient: server.Client(), baseURL: server.URL, StrictTTL: true}
+	records := []libdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: 2 * time.Minute}}
+
+	if _, err := p.AppendRecords(context.Background(), "example.com.", records); err != nil {
+		t.Errorf("Expected a TTL above the zone's minimum to be accepted but got %v", err)
+	}
+
+	if _, err := p.SetRecords(context.Background(), "example.com.", records); err != nil {
+		t.Errorf("Expected a TTL above the zone's minimum to be accepted but got %v", err)
+	}
+
+	if errs := p.ValidateRecords(context.Background(), "example.com.", records); errs != nil {
+		t.Errorf("Expected a TTL above the zone's minimum to be valid but got %v", errs)
+	}
+
+	records[0].TTL = 30 * time.Second
+
+	if _, err := p.AppendRecords(context.Background(), "example.com.", records); !errors.Is(err, ErrTTLBelowMinimum) {
+		t.Errorf("Expected ErrTTLBelowMinimum but got %v", err)
+	}
+
+	if errs := p.ValidateRecords(context.Background(), "example.com.", records); len(errs) != 1 || !errors.Is(errs[0], ErrTTLBelowMinimum) {
+		t.Errorf("Expected ErrTTLBelowMinimum but got %v", errs)
+	}
+}
+
+func TestSetZoneTTLMixedSet(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[
+				{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+				{"name": "", "type": "NS", "data": "ns.example.net.", "ttl": 3600, "scope": "member"}
+			]`))
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("type")+" "+r.PostForm.Get("data")+" "+r.PostForm.Get("ttl"))
+		}
+	})
+
+	updated, err := p.SetZoneTTL(context.Background(), "example.com.", 900*time.Second)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// Replacing the set would remove the system NS record too
+	expected := []string{
+		"removeRR NS ns.example.net. 3600",
+		"addRR NS ns.example.net. 900",
+	}
+
+	if !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("Expected %v but got %v", expected, mutations)
+	}
+
+	if len(updated) != 1 || updated[0].TTL != 900*time.Second {
+		t.Errorf("Expected the NS record with TTL 900s but got %+v", updated)
+	}
+}
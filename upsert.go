@@ -0,0 +1,65 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/nfsn/convert"
+)
+
+// UpsertRecord makes `record` the only record with its name and type: if the zone already has
+// records with that name and type they're replaced with it, otherwise it's added. Nothing is
+// written if the zone already has exactly this record (see `Equal`). Returns the record.
+func (p *Provider) UpsertRecord(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
+	records, err := relativeRecords(zone, []libdns.Record{record})
+
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	relative := records[0]
+	ctx = p.withZoneMinTTL(ctx, zone)
+	_, err = p.validateRecords(ctx, records)
+
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	name, err := convert.WildcardName(relative.Name)
+
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	matches, err := p.getRecords(ctx, zone, filterName(name), Normalize(relative).Type)
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return libdns.Record{}, err
+	}
+
+	var existing []libdns.Record
+
+	// NFSN may match names differently, e.g. ignoring case, so check every match
+	for _, match := range matches {
+		if rrSetKeyFor(match) == rrSetKeyFor(relative) {
+			existing = append(existing, match)
+		}
+	}
+
+	switch {
+	case len(existing) == 1 && p.syncEqual(ctx, existing[0], relative):
+		return record, nil
+	case len(existing) == 0:
+		_, err = p.processRecords(ctx, zone, "addRR", records)
+	default:
+		_, err = p.replaceGroups(ctx, zone, records)
+	}
+
+	if err != nil {
+		return libdns.Record{}, err
+	}
+
+	return record, nil
+}
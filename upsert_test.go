@@ -0,0 +1,95 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestUpsertRecord(t *testing.T) {
+	var mutations []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/listRRs":
+			if r.PostForm.Get("name") == "www" {
+				w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 3600, "scope": "member"}]`))
+			} else {
+				w.Write([]byte(`[]`))
+			}
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("name")+" "+r.PostForm.Get("data"))
+		}
+	})
+
+	cases := []struct {
+		record   libdns.Record
+		mutation []string
+	}{
+		{libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: time.Hour}, nil},
+		{libdns.Record{Type: "A", Name: "www.example.com.", Value: "192.0.2.2", TTL: time.Hour}, []string{"replaceRR www 192.0.2.2"}},
+		{libdns.Record{Type: "A", Name: "mail", Value: "192.0.2.3"}, []string{"addRR mail 192.0.2.3"}},
+	}
+
+	for _, c := range cases {
+		mutations = nil
+		upserted, err := p.UpsertRecord(context.Background(), "example.com.", c.record)
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if upserted != c.record {
+			t.Errorf("Expected %+v but got %+v", c.record, upserted)
+		}
+
+		if !reflect.DeepEqual(mutations, c.mutation) {
+			t.Errorf("Expected %v but got %v", c.mutation, mutations)
+		}
+	}
+}
+
+func TestUpsertRecordUsesZoneMinTTL(t *testing.T) {
+	var mutations []string
+
+	p := newRawTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/minTTL":
+			w.Write([]byte("60"))
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 120, "scope": "member"}]`))
+		default:
+			mutations = append(mutations, r.URL.Path[len("/dns/example.com/"):]+" "+r.PostForm.Get("ttl"))
+		}
+	})
+
+	// Both TTLs are below the default minimum, but not the zone's
+	cases := []struct {
+		ttl      time.Duration
+		mutation []string
+	}{
+		{2 * time.Minute, nil},
+		{time.Minute, []string{"replaceRR 60"}},
+	}
+
+	for _, c := range cases {
+		mutations = nil
+		_, err := p.UpsertRecord(context.Background(), "example.com.", libdns.Record{Type: "A", Name: "www", Value: "192.0.2.1", TTL: c.ttl})
+
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if !reflect.DeepEqual(mutations, c.mutation) {
+			t.Errorf("Expected %v but got %v", c.mutation, mutations)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/libdns/libdns"
+)
+
+// If `VerifyAfterWrite` is enabled and the write succeeded, re-fetches the zone and returns the
+// records in the same (name, type) groups as `written`. Otherwise returns `written` and `err`
+// unchanged.
+func (p *Provider) verifyWrite(ctx context.Context, zone string, written []libdns.Record, err error) ([]libdns.Record, error) {
+	if err != nil || !p.VerifyAfterWrite || len(written) == 0 {
+		return written, err
+	}
+
+	stored, err := p.storedSets(ctx, zone, written)
+
+	if err != nil {
+		return written, err
+	}
+
+	return stored[0], nil
+}
+
+// Re-fetches the zone and returns, for each of `groups`, the records now in the zone in the same
+// (name, type) groups as the records in the group, with names as GetRecords would return them.
+func (p *Provider) storedSets(ctx context.Context, zone string, groups ...[]libdns.Record) ([][]libdns.Record, error) {
+	current, err := p.GetRecords(ctx, zone)
+	var parseErrors RecordParseErrors
+
+	if err != nil && !errors.As(err, &parseErrors) {
+		return nil, fmt.Errorf("Records were written but could not be re-fetched: %w", err)
+	}
+
+	stored := make([][]libdns.Record, len(groups))
+
+	for i, group := range groups {
+		keys := make(map[rrSetKey]bool)
+
+		for _, record := range group {
+			keys[rrSetKeyFor(record)] = true
+		}
+
+		for _, record := range current {
+			name, err := relativeName(record.Name, zone)
+
+			if err != nil {
+				continue
+			}
+
+			relative := record
+			relative.Name = name
+
+			if keys[rrSetKeyFor(relative)] {
+				stored[i] = append(stored[i], record)
+			}
+		}
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,65 @@
+package nfsn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestVerifyAfterWrite(t *testing.T) {
+	var stored []string
+
+	// Stores records with their data lowercased, and with the minimum TTL
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.URL.Path {
+		case "/dns/example.com/addRR":
+			stored = append(stored, fmt.Sprintf(`{"name": %q, "type": %q, "data": %q, "ttl": 180}`,
+				r.PostForm.Get("name"), r.PostForm.Get("type"), strings.ToLower(r.PostForm.Get("data"))))
+		case "/dns/example.com/listRRs":
+			w.Write([]byte("[" + strings.Join(stored, ",") + `, {"name": "other", "type": "A", "data": "192.0.2.1", "ttl": 180}]`))
+		}
+	})
+	p.VerifyAfterWrite = true
+
+	records := []libdns.Record{{Type: "CNAME", Name: "www", Value: "Example.NET."}}
+	added, err := p.AppendRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(added) != 1 {
+		t.Fatalf("Expected 1 record but got %+v", added)
+	}
+
+	if added[0].Value != "example.net." || added[0].TTL.Seconds() != 180 {
+		t.Errorf("Expected the record as stored by NFSN but got %+v", added[0])
+	}
+}
+
+func TestVerifyAfterWriteNames(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns/example.com/listRRs" {
+			w.Write([]byte(`[{"name": "www", "type": "A", "data": "192.0.2.1", "ttl": 180, "scope": "member"}]`))
+		}
+	})
+	p.VerifyAfterWrite = true
+	p.AbsoluteNames = true
+
+	records := []libdns.Record{{Type: "A", Name: "WWW.example.com.", Value: "192.0.2.1"}}
+	replaced, err := p.SetRecords(context.Background(), "example.com.", records)
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(replaced) != 1 || replaced[0].Name != "www.example.com." {
+		t.Errorf("Expected the record as GetRecords returns it but got %+v", replaced)
+	}
+}
@@ -0,0 +1,104 @@
+package nfsn
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The domain NFSN's own nameservers are in
+const nfsnNameserverDomain = "nearlyfreespeech.net."
+
+// ZoneInfo describes a zone's SOA record and nameservers.
+type ZoneInfo struct {
+	// The SOA serial. NFSN increments it whenever the zone changes, so comparing it against a later
+	// call reveals whether the zone has changed in between.
+	Serial uint32
+
+	// The SOA timers for secondary nameservers
+	Refresh time.Duration
+	Retry   time.Duration
+	Expire  time.Duration
+
+	// The minimum TTL NFSN allows for records in the zone
+	MinTTL time.Duration
+
+	// The zone's NS records at the apex
+	Nameservers []string
+
+	// True if any of Nameservers is one of NFSN's, i.e. NFSN is serving the zone. It only reflects the
+	// zone's own NS records; the delegation in the parent zone isn't checked.
+	Authoritative bool
+}
+
+// GetZoneInfo reads the zone's SOA values, which NFSN exposes as properties of the zone, and its NS
+// records.
+func (p *Provider) GetZoneInfo(ctx context.Context, zone string) (ZoneInfo, error) {
+	var info ZoneInfo
+	serial, err := p.getSerial(ctx, zone)
+
+	if err != nil {
+		return ZoneInfo{}, err
+	}
+
+	info.Serial = serial
+	durations := []struct {
+		property string
+		value    *time.Duration
+	}{
+		{"refresh", &info.Refresh},
+		{"retry", &info.Retry},
+		{"expire", &info.Expire},
+		{"minTTL", &info.MinTTL},
+	}
+
+	for _, duration := range durations {
+		*duration.value, err = p.getZoneDuration(ctx, zone, duration.property)
+
+		if err != nil {
+			return ZoneInfo{}, err
+		}
+	}
+
+	info.Nameservers, err = p.zoneNameservers(ctx, zone)
+
+	if err != nil {
+		return ZoneInfo{}, err
+	}
+
+	for _, nameserver := range info.Nameservers {
+		host := strings.ToLower(strings.TrimSuffix(nameserver, ".") + ".")
+
+		if strings.HasSuffix(host, "."+nfsnNameserverDomain) {
+			info.Authoritative = true
+		}
+	}
+
+	return info, nil
+}
+
+// Reads the property `name` of the zone, a number of seconds.
+func (p *Provider) getZoneDuration(ctx context.Context, zone string, name string) (time.Duration, error) {
+	text, err := p.getZoneProperty(ctx, zone, name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	seconds, err := strconv.ParseUint(text, 10, 32)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to parse %s %q for zone %s: %w", name, text, zone, err)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// BumpSerial increments the zone's SOA serial without changing any records, so that secondary
+// nameservers transfer the zone again, e.g. after a change NFSN made outside of the API.
+func (p *Provider) BumpSerial(ctx context.Context, zone string) error {
+	_, err := p.makeRequest(ctx, "POST", p.uriForZone(zone, "updateSerial"), nil)
+	return err
+}
@@ -0,0 +1,83 @@
+package nfsn
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetZoneInfo(t *testing.T) {
+	nameservers := `[
+		{"name": "", "type": "NS", "data": "ns.phx1.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"},
+		{"name": "", "type": "NS", "data": "ns.phx2.nearlyfreespeech.net.", "ttl": 3600, "scope": "system"}
+	]`
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dns/example.com/serial":
+			w.Write([]byte("2024010101"))
+		case "/dns/example.com/refresh":
+			w.Write([]byte("86400"))
+		case "/dns/example.com/retry":
+			w.Write([]byte(`"7200"`))
+		case "/dns/example.com/expire":
+			w.Write([]byte("3600000"))
+		case "/dns/example.com/listRRs":
+			w.Write([]byte(nameservers))
+		}
+	})
+
+	info, err := p.GetZoneInfo(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := ZoneInfo{
+		Serial:        2024010101,
+		Refresh:       24 * time.Hour,
+		Retry:         2 * time.Hour,
+		Expire:        1000 * time.Hour,
+		MinTTL:        180 * time.Second,
+		Nameservers:   []string{"ns.phx1.nearlyfreespeech.net.", "ns.phx2.nearlyfreespeech.net."},
+		Authoritative: true,
+	}
+
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, info)
+	}
+
+	// Delegated elsewhere
+	nameservers = `[{"name": "", "type": "NS", "data": "ns1.example.net.", "ttl": 3600, "scope": "member"}]`
+	info, err = p.GetZoneInfo(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if info.Authoritative {
+		t.Errorf("Expected a zone served by ns1.example.net. not to be authoritative")
+	}
+}
+
+func TestBumpSerial(t *testing.T) {
+	var requests []string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+	})
+
+	err := p.BumpSerial(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := []string{"POST /dns/example.com/updateSerial"}
+
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected %v but got %v", expected, requests)
+	}
+}
@@ -0,0 +1,140 @@
+package nfsn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrZoneNotFound is returned when NFSN doesn't recognize a zone, because it doesn't exist or isn't
+// managed by the account. Unlike most API errors, retrying won't help; the zone is probably
+// misconfigured.
+var ErrZoneNotFound = errors.New("Zone not found")
+
+// Returns an error wrapping ErrZoneNotFound if `resp`, a failed response to a request for
+// `requestURL`, shows that the zone in the URL doesn't exist. Returns nil otherwise.
+//
+// NFSN responds 404 Not Found to any request for an unknown DNS object, including verbs and
+// properties that an existing zone doesn't have, so a 404 only counts once reading the zone's
+// `minTTL` property, which every zone has, fails with 404 too.
+func (p *Provider) zoneNotFoundError(ctx context.Context, requestURL string, resp *http.Response, err error) error {
+	if resp.StatusCode != http.StatusNotFound {
+		return nil
+	}
+
+	zoneURL, zone, ok := splitZoneURL(requestURL)
+
+	if !ok {
+		return nil
+	}
+
+	if !strings.HasSuffix(requestURL, "/minTTL") && !p.zoneMissing(ctx, zoneURL) {
+		return nil
+	}
+
+	return &sentinelError{sentinel: ErrZoneNotFound, err: fmt.Errorf("%s: %w", zone, err)}
+}
+
+// Returns whether NFSN reports that the zone at `zoneURL` (see `splitZoneURL`) doesn't exist. Returns
+// false if that can't be determined.
+func (p *Provider) zoneMissing(ctx context.Context, zoneURL string) bool {
+	resp, err := p.sendRequest(ctx, "GET", zoneURL+"minTTL", nil)
+	return err == nil && resp.StatusCode == http.StatusNotFound
+}
+
+// Splits a URL for a zone's verb or property into the URL of the zone, including the trailing slash,
+// and the zone's name. Returns false if `requestURL` isn't for a zone.
+func splitZoneURL(requestURL string) (string, string, bool) {
+	base, rest, ok := strings.Cut(requestURL, "/dns/")
+
+	if !ok {
+		return "", "", false
+	}
+
+	zone, _, ok := strings.Cut(rest, "/")
+
+	if !ok {
+		return "", "", false
+	}
+
+	return base + "/dns/" + zone + "/", zone, true
+}
+
+// ListZones lists the DNS zones (domains) in the member account, as reported by the member's
+// `domains` property. An account with no domains results in an empty slice.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	login, _, err := p.credentials(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.makeRequest(ctx, "GET", p.uriForMember(login, "domains"), nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []string
+
+	if len(strings.TrimSpace(string(bodyBytes))) > 0 {
+		err = json.Unmarshal(bodyBytes, &domains)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	zones := make([]libdns.Zone, 0, len(domains))
+
+	for _, domain := range domains {
+		zones = append(zones, libdns.Zone{Name: strings.TrimRight(domain, ".") + "."})
+	}
+
+	return zones, nil
+}
+
+// FindZone finds the zone in the member account that `fqdn` belongs to, i.e. the listed zone that
+// is the longest suffix of it, and returns the zone along with `fqdn` relative to it, so callers
+// with only a fully qualified name don't need to know where the zone cut is. The name is "" if
+// `fqdn` is the zone itself. Returns an error wrapping ErrZoneNotFound if no zone matches.
+func (p *Provider) FindZone(ctx context.Context, fqdn string) (zone string, name string, err error) {
+	zones, err := p.ListZones(ctx)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	absolute := strings.TrimSuffix(fqdn, ".") + "."
+
+	for _, candidate := range zones {
+		candidateName, err := relativeName(absolute, candidate.Name)
+
+		if err != nil {
+			continue
+		}
+
+		if zone == "" || len(candidate.Name) > len(zone) {
+			zone = candidate.Name
+			name = candidateName
+		}
+	}
+
+	if zone == "" {
+		return "", "", fmt.Errorf("%w: no zone in the account contains %s", ErrZoneNotFound, fqdn)
+	}
+
+	return zone, name, nil
+}
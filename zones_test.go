@@ -0,0 +1,120 @@
+package nfsn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestListZones(t *testing.T) {
+	body := `["example.com", "example.net"]`
+	var path string
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(body))
+	})
+
+	zones, err := p.ListZones(context.Background())
+
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if path != "/member/testuser/domains" {
+		t.Errorf("Expected '/member/testuser/domains' but got '%s'", path)
+	}
+
+	if len(zones) != 2 || zones[0].Name != "example.com." || zones[1].Name != "example.net." {
+		t.Errorf("Unexpected zones %+v", zones)
+	}
+
+	for _, body = range []string{"[]", ""} {
+		zones, err = p.ListZones(context.Background())
+
+		if err != nil {
+			t.Errorf("%q: Unexpected error %v", body, err)
+		}
+
+		if zones == nil || len(zones) != 0 {
+			t.Errorf("%q: Expected an empty, non-nil slice but got %#v", body, zones)
+		}
+	}
+}
+
+func TestZoneNotFound(t *testing.T) {
+	requests := 0
+
+	p := newRawTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "Object not found."}`))
+	})
+
+	_, err := p.GetRecords(context.Background(), "missing.example.")
+
+	if !errors.Is(err, ErrZoneNotFound) || !strings.Contains(err.Error(), "missing.example") {
+		t.Errorf("Expected ErrZoneNotFound but got %v", err)
+	}
+
+	// The 404 is confirmed by reading the zone's minTTL property
+	if requests != 2 {
+		t.Errorf("Expected 2 requests but got %d", requests)
+	}
+
+	// A 404 for a zone that exists, e.g. for an unknown verb, isn't about the zone
+	p = newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	_, err = p.GetRecords(context.Background(), "example.com.")
+
+	if err == nil || errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("Expected an error other than ErrZoneNotFound but got %v", err)
+	}
+
+	// Other 404s aren't about zones
+	_, err = p.ListZones(context.Background())
+
+	if err == nil || errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("Expected an error other than ErrZoneNotFound but got %v", err)
+	}
+}
+
+func TestFindZone(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["example.com", "sub.example.com", "example.net"]`))
+	})
+
+	cases := []struct {
+		fqdn string
+		zone string
+		name string
+	}{
+		{"www.example.com.", "example.com.", "www"},
+		{"_acme-challenge.www.sub.example.com", "sub.example.com.", "_acme-challenge.www"},
+		{"WWW.Example.NET.", "example.net.", "www"},
+		{"sub.example.com.", "sub.example.com.", ""},
+		{"notsub.example.com.", "example.com.", "notsub"},
+	}
+
+	for _, c := range cases {
+		zone, name, err := p.FindZone(context.Background(), c.fqdn)
+
+		if err != nil {
+			t.Errorf("%s: Unexpected error %v", c.fqdn, err)
+			continue
+		}
+
+		if zone != c.zone || name != c.name {
+			t.Errorf("%s: Expected '%s' in '%s' but got '%s' in '%s'", c.fqdn, c.name, c.zone, name, zone)
+		}
+	}
+
+	_, _, err := p.FindZone(context.Background(), "www.example.org.")
+
+	if !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("Expected ErrZoneNotFound but got %v", err)
+	}
+}